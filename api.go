@@ -8,3 +8,9 @@ func NewApi() api.Api {
 	// this _should_ point to the production site
 	return api.NewApi(api.Options{})
 }
+
+// NewApiWithOptions creates an api configured by opts, see the With*
+// functions in the api package for what can be set
+func NewApiWithOptions(opts ...api.Option) (api.Api, error) {
+	return api.NewApiWithOptions(opts...)
+}
@@ -3,6 +3,10 @@ module github.com/jonorademaker/finance_api_client
 go 1.16
 
 require (
-	github.com/google/uuid v1.2.0 // indirect
-	github.com/stretchr/testify v1.7.0 // indirect
+	github.com/google/uuid v1.2.0
+	github.com/prometheus/client_golang v1.11.1
+	github.com/stretchr/testify v1.7.1
+	go.opentelemetry.io/otel v1.7.0
+	go.opentelemetry.io/otel/sdk v1.7.0
+	go.opentelemetry.io/otel/trace v1.7.0
 )
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"context"
+
+	"github.com/jonorademaker/finance_api_client/pkg/models"
+)
+
+// IteratorOptions configures how an AccountIterator walks the pages of accounts
+type IteratorOptions struct {
+	// PageSize is the number of accounts requested per page,
+	// the api's default is used when it's 0
+	PageSize int
+	Filter   AccountFilter
+	// Prefetch requests the next page on a goroutine while
+	// the accounts of the current page are being read
+	Prefetch bool
+}
+
+// AccountIterator lazily walks every page of accounts by following the "next"
+// link of each page. Typical use is
+//
+//	it := api.OrganisationalAccounts.Iterate(IteratorOptions{PageSize: 100})
+//	for it.Next() {
+//		account := it.Account()
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+//
+// An AccountIterator is not safe for concurrent use
+type AccountIterator struct {
+	ctx         context.Context
+	accountsApi *organisationalAccounts
+	options     IteratorOptions
+
+	page    []models.OrganisationAccount
+	index   int
+	account models.OrganisationAccount
+
+	started     bool
+	nextLink    string
+	fetched     map[string]bool
+	prefetching chan pageResult
+
+	done bool
+	err  error
+}
+
+type pageResult struct {
+	list models.OrganisationAccountList
+	err  error
+}
+
+func (accountsApi *organisationalAccounts) Iterate(options IteratorOptions) *AccountIterator {
+	return accountsApi.IterateWithContext(context.Background(), options)
+}
+
+// IterateWithContext behaves like Iterate, cancelling ctx stops the
+// iteration and aborts any page being fetched
+func (accountsApi *organisationalAccounts) IterateWithContext(ctx context.Context, options IteratorOptions) *AccountIterator {
+	return &AccountIterator{
+		ctx:         ctx,
+		accountsApi: accountsApi,
+		options:     options,
+		fetched:     map[string]bool{},
+	}
+}
+
+// Next moves to the next account, fetching another page if needed. It returns
+// false once every account has been read or an error occurred, see Err
+func (it *AccountIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+
+	for it.index >= len(it.page) {
+		if it.done || !it.loadNextPage() {
+			return false
+		}
+	}
+
+	it.account = it.page[it.index]
+	it.index += 1
+	return true
+}
+
+// Account is the account Next moved to
+func (it *AccountIterator) Account() models.OrganisationAccount {
+	return it.account
+}
+
+// Err is the error that stopped the iteration, nil if every page was read
+func (it *AccountIterator) Err() error {
+	return it.err
+}
+
+func (it *AccountIterator) loadNextPage() bool {
+	var result pageResult
+	if it.prefetching != nil {
+		select {
+		case result = <-it.prefetching:
+		case <-it.ctx.Done():
+			result = pageResult{err: models.NewTransportError(it.nextLink, it.ctx.Err())}
+		}
+		it.prefetching = nil
+	} else {
+		result = it.fetchPage(!it.started, it.nextLink)
+	}
+	it.started = true
+
+	if result.err != nil {
+		it.err = result.err
+		it.done = true
+		return false
+	}
+
+	it.page = result.list.Accounts
+	it.index = 0
+	it.nextLink = result.list.Links.Next
+
+	// the api should leave out the next link on the last page, but an empty
+	// page or a link we've already followed would otherwise loop forever
+	if it.nextLink == "" || len(it.page) == 0 || it.fetched[it.nextLink] {
+		it.done = true
+		return true
+	}
+	it.fetched[it.nextLink] = true
+
+	if it.options.Prefetch {
+		it.prefetching = make(chan pageResult, 1)
+		go func(link string, prefetching chan<- pageResult) {
+			prefetching <- it.fetchPage(false, link)
+		}(it.nextLink, it.prefetching)
+	}
+	return true
+}
+
+func (it *AccountIterator) fetchPage(first bool, link string) pageResult {
+	if first {
+		list, err := it.accountsApi.ListWithContext(it.ctx, ListOptions{
+			PageSize: it.options.PageSize,
+			Filter:   it.options.Filter,
+		})
+		return pageResult{list: list, err: err}
+	}
+
+	// the next page is the same as a call to List
+	ctx, end := it.accountsApi.baseApi.startOperation(it.ctx, "OrganisationalAccounts.List")
+	responseBody, err := it.accountsApi.baseApi.getLink(ctx, link)
+	if err != nil {
+		end(err)
+		return pageResult{err: err}
+	}
+
+	list, err := models.DeserializeAccountListJson(responseBody)
+	end(err)
+	return pageResult{list: list, err: err}
+}
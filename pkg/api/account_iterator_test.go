@@ -0,0 +1,171 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// pagedAccountsServer serves pages of accounts with ids "0-0", "0-1", "1-0"...
+// where the last page has no next link
+func pagedAccountsServer(t *testing.T, pages int, perPage int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := 0
+		if number := r.URL.Query().Get("page[number]"); number != "" {
+			_, err := fmt.Sscan(number, &page)
+			assert.NoError(t, err)
+		}
+
+		data := ""
+		for i := 0; i < perPage; i++ {
+			if i > 0 {
+				data += ","
+			}
+			data += fmt.Sprintf(`{"type": "accounts", "id": "%d-%d"}`, page, i)
+		}
+
+		next := ""
+		if page < pages-1 {
+			next = fmt.Sprintf(`"next": "/v1/organisation/accounts?page%%5Bnumber%%5D=%d&page%%5Bsize%%5D=%d",`, page+1, perPage)
+		}
+
+		_, err := fmt.Fprintf(w, `{"data": [%s], "links": {%s "self": "%s"}}`, data, next, r.URL)
+		assert.NoError(t, err)
+	}))
+}
+
+func collectAccountIds(it *AccountIterator) []string {
+	ids := []string{}
+	for it.Next() {
+		ids = append(ids, it.Account().ID)
+	}
+	return ids
+}
+
+func TestAccountIteratorWalksEveryPage(t *testing.T) {
+	for _, prefetch := range []bool{false, true} {
+		server := pagedAccountsServer(t, 3, 2)
+		api := NewApi(Options{baseUrl: server.URL, httpClient: server.Client()})
+
+		it := api.OrganisationalAccounts.Iterate(IteratorOptions{PageSize: 2, Prefetch: prefetch})
+		ids := collectAccountIds(it)
+
+		assert.NoError(t, it.Err())
+		assert.Equal(t, []string{"0-0", "0-1", "1-0", "1-1", "2-0", "2-1"}, ids)
+		assert.False(t, it.Next())
+		server.Close()
+	}
+}
+
+func TestAccountIteratorEmptyList(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte(`{"data": [], "links": {"next": "/v1/organisation/accounts?page%5Bnumber%5D=1"}}`))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+	api := NewApi(Options{baseUrl: server.URL, httpClient: server.Client()})
+
+	it := api.OrganisationalAccounts.Iterate(IteratorOptions{})
+
+	assert.False(t, it.Next())
+	assert.NoError(t, it.Err())
+}
+
+func TestAccountIteratorStopsOnRepeatedLink(t *testing.T) {
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count += 1
+		_, err := w.Write([]byte(`{"data": [{"type": "accounts", "id": "1"}], "links": {"next": "/v1/organisation/accounts?page%5Bnumber%5D=1"}}`))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+	api := NewApi(Options{baseUrl: server.URL, httpClient: server.Client()})
+
+	it := api.OrganisationalAccounts.Iterate(IteratorOptions{})
+	ids := collectAccountIds(it)
+
+	assert.NoError(t, it.Err())
+	assert.Equal(t, []string{"1", "1"}, ids)
+	assert.Equal(t, 2, count)
+}
+
+func TestAccountIteratorRejectsForeignLink(t *testing.T) {
+	foreign := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("request sent to the foreign host %s", r.URL)
+	}))
+	defer foreign.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := fmt.Fprintf(w, `{"data": [{"type": "accounts", "id": "1"}], "links": {"next": "%s/v1/organisation/accounts?page%%5Bnumber%%5D=1"}}`, foreign.URL)
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+	api := NewApi(Options{baseUrl: server.URL, httpClient: server.Client()})
+
+	it := api.OrganisationalAccounts.Iterate(IteratorOptions{})
+	ids := collectAccountIds(it)
+
+	assert.Equal(t, []string{"1"}, ids)
+	assert.EqualError(t, it.Err(), fmt.Sprintf(`Error - link "%s/v1/organisation/accounts?page%%5Bnumber%%5D=1" isn't to the api at %s`, foreign.URL, server.URL))
+}
+
+func TestAccountIteratorFollowsAbsoluteLink(t *testing.T) {
+	var serverUrl string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page[number]") == "1" {
+			_, err := w.Write([]byte(`{"data": [{"type": "accounts", "id": "2"}]}`))
+			assert.NoError(t, err)
+			return
+		}
+		_, err := fmt.Fprintf(w, `{"data": [{"type": "accounts", "id": "1"}], "links": {"next": "%s/v1/organisation/accounts?page%%5Bnumber%%5D=1"}}`, serverUrl)
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+	serverUrl = server.URL
+	api := NewApi(Options{baseUrl: server.URL, httpClient: server.Client()})
+
+	it := api.OrganisationalAccounts.Iterate(IteratorOptions{})
+	ids := collectAccountIds(it)
+
+	assert.NoError(t, it.Err())
+	assert.Equal(t, []string{"1", "2"}, ids)
+}
+
+func TestAccountIteratorStopsOnError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page[number]") == "1" {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, err := w.Write([]byte(`{"error_message": "database unavailable"}`))
+			assert.NoError(t, err)
+			return
+		}
+		_, err := w.Write([]byte(`{"data": [{"type": "accounts", "id": "1"}], "links": {"next": "/v1/organisation/accounts?page%5Bnumber%5D=1"}}`))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+	api := NewApi(Options{baseUrl: server.URL, httpClient: server.Client()})
+
+	it := api.OrganisationalAccounts.Iterate(IteratorOptions{Prefetch: true})
+	ids := collectAccountIds(it)
+
+	assert.Equal(t, []string{"1"}, ids)
+	assert.EqualError(t, it.Err(), "Error (Status 500) - database unavailable")
+	assert.False(t, it.Next())
+}
+
+func TestAccountIteratorCancelledContext(t *testing.T) {
+	server := pagedAccountsServer(t, 3, 1)
+	defer server.Close()
+	api := NewApi(Options{baseUrl: server.URL, httpClient: server.Client()})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	it := api.OrganisationalAccounts.IterateWithContext(ctx, IteratorOptions{PageSize: 1})
+
+	assert.True(t, it.Next())
+	cancel()
+	assert.False(t, it.Next())
+	assert.Error(t, it.Err())
+}
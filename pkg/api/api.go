@@ -2,41 +2,50 @@ package api
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/jonorademaker/finance_api_client/pkg/models"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Api struct {
 	OrganisationalAccounts organisationalAccounts
+	baseApi                baseApi
 }
 
 type baseApi struct {
-	baseUrl       string
-	client        *http.Client
-	logger        *log.Logger
-	retryStrategy retryStrategy
+	baseUrl string
+	client  *http.Client
+	logger  Logger
+	// tracer is nil unless tracing has been enabled
+	tracer trace.Tracer
+	// transport sends requests through the middleware chain to client
+	transport http.RoundTripper
 }
 
 type Options struct {
 	baseUrl               string
 	httpClient            *http.Client
 	timeoutInMilliseconds int
-	logger                *log.Logger
-	retryStrategy         retryStrategy
+	logger                Logger
+	logSensitiveData      bool
+	retryStrategy         RetryStrategy
+	signer                RequestSigner
+	authenticator         Authenticator
+	middlewares           []Middleware
+	tracerProvider        trace.TracerProvider
+	metrics               Metrics
+	circuitBreaker        *CircuitBreaker
 }
 
 func createBaseApi(options Options) baseApi {
-	var logger *log.Logger
-	if options.logger != nil {
-		logger = options.logger
-		logger.SetPrefix("financeapi - ")
-	}
-
 	var client *http.Client
 	if options.httpClient == nil {
 		client = &http.Client{}
@@ -44,7 +53,13 @@ func createBaseApi(options Options) baseApi {
 		client = options.httpClient
 	}
 
-	client.Timeout = time.Duration(options.timeoutInMilliseconds) * time.Millisecond
+	if options.timeoutInMilliseconds > 0 {
+		// copy the client so we don't change the timeout of a client
+		// the caller may be using elsewhere
+		timeoutClient := *client
+		timeoutClient.Timeout = time.Duration(options.timeoutInMilliseconds) * time.Millisecond
+		client = &timeoutClient
+	}
 
 	var baseUrl string
 	if options.baseUrl == "" {
@@ -53,14 +68,46 @@ func createBaseApi(options Options) baseApi {
 		baseUrl = options.baseUrl
 	}
 
-	var retryStrategy retryStrategy
+	var retryStrategy RetryStrategy
 	if options.retryStrategy != nil {
 		retryStrategy = options.retryStrategy
 	} else {
-		retryStrategy = func(_ RetryOptions) bool { return false }
+		retryStrategy = NoRetries
 	}
 
-	return baseApi{baseUrl: baseUrl, client: client, logger: logger, retryStrategy: retryStrategy}
+	// retrying is outermost so the middlewares given see every attempt, while
+	// authenticating and signing are innermost so they cover any changes made.
+	// The circuit breaker comes next so attempts it stops aren't sent anywhere
+	middlewares := []Middleware{RetryMiddleware(retryStrategy)}
+	if options.circuitBreaker != nil {
+		middlewares = append(middlewares, circuitBreakerMiddleware(options.circuitBreaker, baseUrl, options.metrics))
+	}
+	var tracer trace.Tracer
+	if options.tracerProvider != nil {
+		tracer = options.tracerProvider.Tracer(instrumentationName)
+		middlewares = append(middlewares, TracingMiddleware(options.tracerProvider))
+	}
+	if options.metrics != nil {
+		middlewares = append(middlewares, MetricsMiddleware(options.metrics))
+	}
+	if options.logger != nil {
+		middlewares = append(middlewares, LoggingMiddleware(options.logger, !options.logSensitiveData))
+	}
+	middlewares = append(middlewares, options.middlewares...)
+	if options.authenticator != nil {
+		middlewares = append(middlewares, AuthenticationMiddleware(options.authenticator))
+	}
+	if options.signer != nil {
+		middlewares = append(middlewares, SigningMiddleware(options.signer))
+	}
+
+	return baseApi{
+		baseUrl:   baseUrl,
+		client:    client,
+		logger:    options.logger,
+		tracer:    tracer,
+		transport: chain(reportCircuitOutcome(clientTransport(client)), middlewares...),
+	}
 }
 
 func NewApi(options Options) Api {
@@ -68,61 +115,129 @@ func NewApi(options Options) Api {
 
 	return Api{
 		OrganisationalAccounts: organisationalAccounts{baseApi: api},
+		baseApi:                api,
 	}
 }
 
-func (api *baseApi) post(resourceUrl string, data []byte) ([]byte, error) {
+func (api *baseApi) post(ctx context.Context, resourceUrl string, data []byte) ([]byte, error) {
 	fullUrl, err := api.buildUrl(resourceUrl, map[string][]string{})
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := api.perform("POST", fullUrl, data)
+	resp, err := api.perform(ctx, "POST", fullUrl, data)
 	if err != nil {
-		return nil, models.FinanceApiError{Err: err}
+		return nil, models.NewTransportError(fullUrl.String(), err)
 	}
 	return api.processResponse(resp)
 }
 
-func (api *baseApi) get(resourceUrl string) ([]byte, error) {
+func (api *baseApi) patch(ctx context.Context, resourceUrl string, data []byte) ([]byte, error) {
 	fullUrl, err := api.buildUrl(resourceUrl, map[string][]string{})
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := api.perform("GET", fullUrl, nil)
+	resp, err := api.perform(ctx, "PATCH", fullUrl, data)
+	if err != nil {
+		return nil, models.NewTransportError(fullUrl.String(), err)
+	}
+	return api.processResponse(resp)
+}
+
+func (api *baseApi) get(ctx context.Context, resourceUrl string, queryString map[string][]string) ([]byte, error) {
+	fullUrl, err := api.buildUrl(resourceUrl, queryString)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := api.perform(ctx, "GET", fullUrl, nil)
+	if err != nil {
+		return nil, models.NewTransportError(fullUrl.String(), err)
+	}
+	return api.processResponse(resp)
+}
+
+// getLink fetches a link returned by the api, e.g. the next page of a list,
+// which is usually relative to the base url but may be absolute as long as
+// it's to the same scheme and host
+func (api *baseApi) getLink(ctx context.Context, link string) ([]byte, error) {
+	linkUrl, err := url.Parse(link)
 	if err != nil {
 		return nil, models.FinanceApiError{Err: err}
 	}
+
+	if linkUrl.IsAbs() || linkUrl.Host != "" {
+		// only follow links to the api itself, requests are signed or carry
+		// a token that mustn't be sent to wherever a response points
+		baseUrl, err := url.Parse(api.baseUrl)
+		if err != nil {
+			return nil, models.FinanceApiError{Err: err}
+		}
+		linkUrl = baseUrl.ResolveReference(linkUrl)
+		if linkUrl.Scheme != baseUrl.Scheme || !strings.EqualFold(linkUrl.Host, baseUrl.Host) {
+			return nil, models.FinanceApiError{Url: link, Err: fmt.Errorf("link %q isn't to the api at %s", link, api.baseUrl)}
+		}
+	} else {
+		linkUrl, err = url.Parse(api.baseUrl + link)
+		if err != nil {
+			return nil, models.FinanceApiError{Err: err}
+		}
+	}
+
+	resp, err := api.perform(ctx, "GET", linkUrl, nil)
+	if err != nil {
+		return nil, models.NewTransportError(linkUrl.String(), err)
+	}
 	return api.processResponse(resp)
 }
 
-func (api *baseApi) delete(resourceUrl string, queryString map[string][]string) error {
+func (api *baseApi) delete(ctx context.Context, resourceUrl string, queryString map[string][]string) error {
 	fullUrl, err := api.buildUrl(resourceUrl, queryString)
 	if err != nil {
 		return err
 	}
 
-	resp, err := api.perform("DELETE", fullUrl, nil)
+	resp, err := api.perform(ctx, "DELETE", fullUrl, nil)
 	if err != nil {
-		return models.FinanceApiError{Err: err}
+		return models.NewTransportError(fullUrl.String(), err)
 	}
 
 	_, err = api.processResponse(resp)
 	return err
 }
 
-func (api *baseApi) perform(method string, url *url.URL, data []byte) (*http.Response, error) {
-	api.ifLog(func(log *log.Logger) {
-		if data != nil {
-			log.Printf("%s %s: %s", method, url, string(data))
-		} else {
-			log.Printf("%s %s", method, url)
-		}
+func (api *baseApi) perform(ctx context.Context, method string, url *url.URL, data []byte) (*http.Response, error) {
+	req, err := newRequest(ctx, method, url, data)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := api.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		return nil, errors.New("no response to the request")
+	}
+
+	// a middleware answering the request itself may leave these unset
+	if resp.Request == nil {
+		resp.Request = req
+	}
+	if resp.Body == nil {
+		resp.Body = http.NoBody
+	}
+	return resp, nil
+}
 
-	})
+func newRequest(ctx context.Context, method string, url *url.URL, data []byte) (*http.Request, error) {
+	var body io.Reader
+	if data != nil {
+		body = bytes.NewReader(data)
+	}
 
-	req, err := http.NewRequest(method, url.String(), bytes.NewReader(data))
+	req, err := http.NewRequestWithContext(ctx, method, url.String(), body)
 	if err != nil {
 		return nil, err
 	}
@@ -132,20 +247,10 @@ func (api *baseApi) perform(method string, url *url.URL, data []byte) (*http.Res
 	req.Header.Add("Date", time.Now().Format(time.RFC3339))
 	req.Header.Add("User-Agent", "FinanceApi Coding Test Lib v0.0.3")
 	req.Header.Add("Accept", "application/vnd.api+json")
-
-	retries := RetryOptions{}
-	for {
-		retries.response, retries.err = api.client.Do(req)
-		retries.count += 1
-
-		if retries.err != nil || !isSuccessResponse(retries.response) {
-			api.ifLog(func(log *log.Logger) { log.Printf("response error: %s", retries.err) })
-			if api.retryStrategy(retries) {
-				continue
-			}
-		}
-		return retries.response, retries.err
+	if data != nil {
+		req.Header.Add("Content-Type", "application/vnd.api+json")
 	}
+	return req, nil
 }
 
 func (api *baseApi) processResponse(resp *http.Response) ([]byte, error) {
@@ -153,18 +258,17 @@ func (api *baseApi) processResponse(resp *http.Response) ([]byte, error) {
 		if resp != nil && resp.Body != nil {
 			err := resp.Body.Close()
 			if err != nil {
-				api.ifLog(func(log *log.Logger) { log.Printf("failed to close request body: %s", err) })
+				api.log(resp.Request.Context(), LevelWarn, "failed to close response body", LogKeyURL, resp.Request.URL, LogKeyError, err)
 			}
 		}
 	}()
 
 	responseBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, models.FinanceApiError{
-			StatusCode: resp.StatusCode,
-			Headers:    resp.Header,
-			Err:        err,
-		}
+		apiError := models.NewTransportError(resp.Request.URL.String(), err)
+		apiError.StatusCode = resp.StatusCode
+		apiError.Headers = resp.Header
+		return nil, apiError
 	}
 
 	if isSuccessResponse(resp) {
@@ -192,11 +296,10 @@ func (api *baseApi) buildUrl(resourceUrl string, queryString map[string][]string
 	return fullUrl, nil
 }
 
-// ifLog takes a function so we don't do unnecessary work in order to do
-// some logging, e.g. decoding bytes to string
-func (api *baseApi) ifLog(logFunc func(logger *log.Logger)) {
+// log writes to the logger, if there is one
+func (api *baseApi) log(ctx context.Context, level Level, msg string, keyvals ...interface{}) {
 	if api.logger != nil {
-		logFunc(api.logger)
+		api.logger.Log(ctx, level, msg, keyvals...)
 	}
 }
 
@@ -1,14 +1,260 @@
 package api
 
-import "net/http"
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
+	"github.com/jonorademaker/finance_api_client/pkg/models"
+)
+
+// RetryOptions describes the attempt that just failed, it's what a
+// RetryStrategy uses to decide whether to try again
 type RetryOptions struct {
-	count    int
-	err      error
-	response *http.Response
+	// Attempt is the number of attempts made so far, starting at 1
+	Attempt int
+	// Elapsed is the time since the first attempt was started
+	Elapsed time.Duration
+	Method  string
+	URL     *url.URL
+	// StatusCode, Header and ApiError describe the response received,
+	// they're left empty when the request failed before getting one
+	StatusCode int
+	Header     http.Header
+	ApiError   *models.FinanceApiError
+	// Err is the error from sending the request or reading the response,
+	// nil when a response was received
+	Err error
 }
 
-// retryStrategy passes in details about the last response that failed
-// the strategy should return true if another attempt should be made
+// RetryStrategy passes in details about the last response that failed
+// the strategy should return true if another attempt should be made,
+// along with how long to wait before making it
 // the strategy should return false if no additional attempts should be made
-type retryStrategy func(retry RetryOptions) bool
+type RetryStrategy func(retry RetryOptions) (bool, time.Duration)
+
+// Jitter is the randomness added to the delays of ExponentialBackoff so
+// clients that failed at the same time don't all retry at the same time
+type Jitter int
+
+const (
+	// NoJitter waits exactly the backoff delay
+	NoJitter Jitter = iota
+	// FullJitter waits a random time between 0 and the backoff delay
+	FullJitter
+	// EqualJitter waits half the backoff delay plus a random time up to the other half
+	EqualJitter
+)
+
+// MaxRetryAfter is the longest a strategy without a maxDelay waits when a response's
+// Retry-After header asks for longer, so a server can't stall the caller indefinitely
+const MaxRetryAfter = time.Minute
+
+// NoRetries never retries, it's the strategy used when none is given
+func NoRetries(_ RetryOptions) (bool, time.Duration) {
+	return false, 0
+}
+
+// ConstantBackoff retries requests accepted by IsRetryable, waiting the same
+// delay between each attempt, until maxAttempts have been made. A Retry-After
+// header is honoured up to MaxRetryAfter
+func ConstantBackoff(delay time.Duration, maxAttempts int) RetryStrategy {
+	return func(retry RetryOptions) (bool, time.Duration) {
+		if retry.Attempt >= maxAttempts || !IsRetryable(retry) {
+			return false, 0
+		}
+
+		return true, retryAfterOr(retry, delay, MaxRetryAfter)
+	}
+}
+
+// ExponentialBackoff retries requests accepted by IsRetryable until maxAttempts
+// have been made. The delay starts at baseDelay, doubles after every attempt and
+// is capped at maxDelay, a maxDelay of 0 leaves it uncapped. A Retry-After header
+// is honoured up to maxDelay, or MaxRetryAfter when it's uncapped
+func ExponentialBackoff(baseDelay time.Duration, maxDelay time.Duration, maxAttempts int, jitter Jitter) RetryStrategy {
+	return func(retry RetryOptions) (bool, time.Duration) {
+		if retry.Attempt >= maxAttempts || !IsRetryable(retry) {
+			return false, 0
+		}
+
+		delay := baseDelay
+		for i := 1; i < retry.Attempt; i++ {
+			// stop doubling once capped, or before the duration overflows
+			if (maxDelay > 0 && delay >= maxDelay) || delay > time.Duration(1<<62) {
+				break
+			}
+			delay *= 2
+		}
+		if maxDelay > 0 && delay > maxDelay {
+			delay = maxDelay
+		}
+
+		retryAfterLimit := maxDelay
+		if retryAfterLimit <= 0 {
+			retryAfterLimit = MaxRetryAfter
+		}
+		return true, retryAfterOr(retry, jitter.apply(delay), retryAfterLimit)
+	}
+}
+
+// IsRetryable is the check the built in strategies use to decide whether a failed
+// attempt can be made again. Requests that never got a response are retryable,
+// unless the circuit breaker stopped them, otherwise only idempotent requests that
+// were rate limited or hit a server error are, so a POST that may have been
+// processed is never repeated
+func IsRetryable(retry RetryOptions) bool {
+	if retry.Err != nil {
+		return !errors.Is(retry.Err, context.Canceled) && !errors.Is(retry.Err, context.DeadlineExceeded) &&
+			!errors.Is(retry.Err, ErrCircuitOpen)
+	}
+	if !isIdempotent(retry.Method) {
+		return false
+	}
+
+	switch retry.StatusCode {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+func (jitter Jitter) apply(delay time.Duration) time.Duration {
+	if delay <= 0 {
+		return 0
+	}
+
+	switch jitter {
+	case FullJitter:
+		return time.Duration(rand.Int63n(int64(delay) + 1))
+	case EqualJitter:
+		half := delay / 2
+		return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+	default:
+		return delay
+	}
+}
+
+// retryAfterOr is the delay asked for by a 429 or 503 response's Retry-After
+// header, no longer than limit, or delay when there isn't one
+func retryAfterOr(retry RetryOptions, delay time.Duration, limit time.Duration) time.Duration {
+	if retry.StatusCode != http.StatusTooManyRequests && retry.StatusCode != http.StatusServiceUnavailable {
+		return delay
+	}
+
+	retryAfter, ok := parseRetryAfter(retry.Header.Get("Retry-After"), time.Now())
+	if !ok {
+		return delay
+	}
+	if retryAfter > limit {
+		return limit
+	}
+	return retryAfter
+}
+
+// parseRetryAfter handles both forms of the header,
+// a number of seconds or an http date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if date.Before(now) {
+		return 0, true
+	}
+	return date.Sub(now), true
+}
+
+// sleepContext waits for delay, returning early with the context's error if it's
+// cancelled first
+func sleepContext(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// recordFailure fills in the details of a failed attempt. The response body is
+// read in full so the error can be parsed, the original body is closed so the
+// connection can be reused and the response is given back with a copy of it
+func (retry *RetryOptions) recordFailure(resp *http.Response, err error) (*http.Response, error) {
+	retry.StatusCode = 0
+	retry.Header = nil
+	retry.ApiError = nil
+	retry.Err = err
+	if err != nil {
+		closeBody(resp)
+		return nil, err
+	}
+
+	if resp.Body == nil {
+		resp.Body = http.NoBody
+	}
+	body, err := io.ReadAll(resp.Body)
+	closeBody(resp)
+	if err != nil {
+		retry.Err = err
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	apiError := models.ParseErrorReponse(retry.URL.String(), resp.StatusCode, resp.Header, body)
+	retry.StatusCode = resp.StatusCode
+	retry.Header = resp.Header
+	retry.ApiError = &apiError
+	return resp, nil
+}
+
+// failure is the reason the attempt failed, for logging
+func (retry RetryOptions) failure() error {
+	if retry.ApiError != nil {
+		return retry.ApiError
+	}
+	return retry.Err
+}
+
+func closeBody(resp *http.Response) {
+	if resp != nil && resp.Body != nil {
+		_ = resp.Body.Close()
+	}
+}
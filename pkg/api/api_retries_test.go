@@ -0,0 +1,209 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func failedResponse(method string, statusCode int, headers map[string]string) RetryOptions {
+	header := http.Header{}
+	for key, value := range headers {
+		header.Set(key, value)
+	}
+	return RetryOptions{Attempt: 1, Method: method, StatusCode: statusCode, Header: header}
+}
+
+func TestIsRetryable(t *testing.T) {
+	assert.True(t, IsRetryable(failedResponse("GET", 503, nil)))
+	assert.True(t, IsRetryable(failedResponse("DELETE", 429, nil)))
+	assert.True(t, IsRetryable(failedResponse("POST", 0, nil).withErr(errors.New("connection refused"))))
+
+	assert.False(t, IsRetryable(failedResponse("POST", 503, nil)))
+	assert.False(t, IsRetryable(failedResponse("GET", 404, nil)))
+	assert.False(t, IsRetryable(failedResponse("GET", 0, nil).withErr(context.Canceled)))
+}
+
+func (retry RetryOptions) withErr(err error) RetryOptions {
+	retry.Err = err
+	retry.StatusCode = 0
+	retry.Header = nil
+	return retry
+}
+
+func TestConstantBackoff(t *testing.T) {
+	strategy := ConstantBackoff(100*time.Millisecond, 3)
+
+	retry := failedResponse("GET", 500, nil)
+	ok, wait := strategy(retry)
+	assert.True(t, ok)
+	assert.Equal(t, 100*time.Millisecond, wait)
+
+	retry.Attempt = 3
+	ok, _ = strategy(retry)
+	assert.False(t, ok)
+}
+
+func TestExponentialBackoffIsCapped(t *testing.T) {
+	strategy := ExponentialBackoff(100*time.Millisecond, time.Second, 100, NoJitter)
+	retry := failedResponse("GET", 502, nil)
+
+	expected := []time.Duration{100, 200, 400, 800, 1000, 1000}
+	for i, delay := range expected {
+		retry.Attempt = i + 1
+		ok, wait := strategy(retry)
+		assert.True(t, ok)
+		assert.Equal(t, delay*time.Millisecond, wait)
+	}
+
+	retry.Attempt = 99
+	_, wait := strategy(retry)
+	assert.Equal(t, time.Second, wait)
+}
+
+func TestExponentialBackoffJitter(t *testing.T) {
+	full := ExponentialBackoff(time.Second, 0, 10, FullJitter)
+	equal := ExponentialBackoff(time.Second, 0, 10, EqualJitter)
+	retry := failedResponse("GET", 500, nil)
+	retry.Attempt = 3
+
+	for i := 0; i < 100; i++ {
+		_, wait := full(retry)
+		assert.True(t, wait >= 0 && wait <= 4*time.Second, wait)
+
+		_, wait = equal(retry)
+		assert.True(t, wait >= 2*time.Second && wait <= 4*time.Second, wait)
+	}
+}
+
+func TestBackoffHonoursRetryAfter(t *testing.T) {
+	strategy := ExponentialBackoff(time.Millisecond, 0, 3, FullJitter)
+
+	_, wait := strategy(failedResponse("GET", 429, map[string]string{"Retry-After": "7"}))
+	assert.Equal(t, 7*time.Second, wait)
+
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	_, wait = strategy(failedResponse("GET", 503, map[string]string{"Retry-After": date}))
+	assert.True(t, wait > 55*time.Second && wait <= time.Minute, wait)
+
+	// a server can't make the caller wait longer than the strategy allows
+	_, wait = strategy(failedResponse("GET", 429, map[string]string{"Retry-After": "86400"}))
+	assert.Equal(t, MaxRetryAfter, wait)
+	_, wait = ExponentialBackoff(time.Millisecond, 5*time.Second, 3, NoJitter)(failedResponse("GET", 429, map[string]string{"Retry-After": "86400"}))
+	assert.Equal(t, 5*time.Second, wait)
+	_, wait = ConstantBackoff(time.Millisecond, 3)(failedResponse("GET", 503, map[string]string{"Retry-After": "86400"}))
+	assert.Equal(t, MaxRetryAfter, wait)
+
+	// only rate limiting and unavailable responses are expected to send the header
+	_, wait = ConstantBackoff(time.Millisecond, 3)(failedResponse("GET", 500, map[string]string{"Retry-After": "7"}))
+	assert.Equal(t, time.Millisecond, wait)
+}
+
+func TestApiWaitsBetweenRetries(t *testing.T) {
+	attempts := []time.Time{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts = append(attempts, time.Now())
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	options := Options{baseUrl: server.URL, httpClient: server.Client(), retryStrategy: ConstantBackoff(50*time.Millisecond, 2)}
+	api := createBaseApi(options)
+
+	_, err := api.get(context.Background(), "/user", nil)
+	assert.Error(t, err)
+	assert.Len(t, attempts, 2)
+	assert.True(t, attempts[1].Sub(attempts[0]) >= 50*time.Millisecond)
+}
+
+func TestApiCancelledContextInterruptsWait(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	options := Options{baseUrl: server.URL, httpClient: server.Client(), retryStrategy: ConstantBackoff(time.Hour, 2)}
+	api := createBaseApi(options)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := api.get(ctx, "/user", nil)
+	assert.EqualError(t, err, "Error - context deadline exceeded")
+	assert.True(t, time.Since(start) < time.Second)
+}
+
+func TestRetryStrategySeesFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		_, err := w.Write([]byte(`{"error_message": "Account cannot be created as it violates a duplicate constraint"}`))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	var seen RetryOptions
+	retry := func(options RetryOptions) (bool, time.Duration) {
+		seen = options
+		return false, 0
+	}
+	api := createBaseApi(Options{baseUrl: server.URL, httpClient: server.Client(), retryStrategy: retry})
+
+	_, err := api.post(context.Background(), "/v1/organisation/accounts", []byte("{}"))
+	assert.EqualError(t, err, "Error (Status 409) - Account cannot be created as it violates a duplicate constraint")
+
+	assert.Equal(t, 1, seen.Attempt)
+	assert.Equal(t, "POST", seen.Method)
+	assert.Equal(t, server.URL+"/v1/organisation/accounts", seen.URL.String())
+	assert.Equal(t, http.StatusConflict, seen.StatusCode)
+	assert.NoError(t, seen.Err)
+	assert.EqualError(t, seen.ApiError, "Error (Status 409) - Account cannot be created as it violates a duplicate constraint")
+}
+
+type closeTrackingBody struct {
+	io.ReadCloser
+	closed *int
+}
+
+func (body closeTrackingBody) Close() error {
+	*body.closed += 1
+	return body.ReadCloser.Close()
+}
+
+type closeTrackingTransport struct {
+	closed int
+	opened int
+}
+
+func (transport *closeTrackingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err == nil {
+		transport.opened += 1
+		resp.Body = closeTrackingBody{ReadCloser: resp.Body, closed: &transport.closed}
+	}
+	return resp, err
+}
+
+func TestDiscardedResponseBodiesAreClosed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, err := w.Write([]byte(`{"error_message": "try again later"}`))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	transport := &closeTrackingTransport{}
+	client := &http.Client{Transport: transport}
+	api := createBaseApi(Options{baseUrl: server.URL, httpClient: client, retryStrategy: ConstantBackoff(0, 3)})
+
+	_, err := api.get(context.Background(), "/user", nil)
+	assert.EqualError(t, err, "Error (Status 503) - try again later")
+	assert.Equal(t, 3, transport.opened)
+	assert.Equal(t, 3, transport.closed)
+}
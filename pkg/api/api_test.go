@@ -1,10 +1,17 @@
 package api
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
+	"time"
 
+	"github.com/jonorademaker/finance_api_client/pkg/models"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -22,18 +29,385 @@ func TestApiRetrySuccess(t *testing.T) {
 	server.Start()
 	defer server.Close()
 
-	retry := func(options RetryOptions) bool {
-		return (options.response.StatusCode < 200 ||
-			options.response.StatusCode >= 300) &&
-			options.count < 2
+	retry := func(options RetryOptions) (bool, time.Duration) {
+		return (options.StatusCode < 200 ||
+			options.StatusCode >= 300) &&
+			options.Attempt < 2, 0
 	}
 	options := Options{baseUrl: server.URL, httpClient: server.Client(), retryStrategy: retry}
 	api := createBaseApi(options)
 
-	resp, err := api.get("/user")
+	resp, err := api.get(context.Background(), "/user", nil)
 	assert.NoError(t, err)
 
 	body := string(resp)
 	assert.Equal(t, "{\"name\": \"John Doe\"}", body)
 	assert.Equal(t, 2, count)
 }
+
+func TestApiCancelledContextStopsRetries(t *testing.T) {
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count += 1
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	retry := func(options RetryOptions) (bool, time.Duration) {
+		cancel()
+		return true, 0
+	}
+	options := Options{baseUrl: server.URL, httpClient: server.Client(), retryStrategy: retry}
+	api := createBaseApi(options)
+
+	_, err := api.get(ctx, "/user", nil)
+	assert.Error(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestApiOrganisationAccountListQuery(t *testing.T) {
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/organisation/accounts", r.URL.Path)
+		query = r.URL.Query()
+		_, err := w.Write([]byte(`{
+  "data": [{"type": "accounts", "id": "48e51a61-29e2-44e6-a97d-4bcf3bda92fc", "attributes": {"country": "GB"}}],
+  "links": {
+    "first": "/v1/organisation/accounts?page%5Bnumber%5D=first&page%5Bsize%5D=1",
+    "next": "/v1/organisation/accounts?page%5Bnumber%5D=3&page%5Bsize%5D=1",
+    "prev": "/v1/organisation/accounts?page%5Bnumber%5D=1&page%5Bsize%5D=1",
+    "last": "/v1/organisation/accounts?page%5Bnumber%5D=last&page%5Bsize%5D=1"
+  }
+}`))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	api := NewApi(Options{baseUrl: server.URL, httpClient: server.Client()})
+
+	list, err := api.OrganisationalAccounts.List(ListOptions{
+		PageNumber: 2,
+		PageSize:   1,
+		Filter:     AccountFilter{Country: "GB", BankIDCode: "GBDSC"},
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, url.Values{
+		"page[number]":         {"2"},
+		"page[size]":           {"1"},
+		"filter[country]":      {"GB"},
+		"filter[bank_id_code]": {"GBDSC"},
+	}, query)
+
+	assert.Len(t, list.Accounts, 1)
+	assert.Equal(t, "48e51a61-29e2-44e6-a97d-4bcf3bda92fc", list.Accounts[0].ID)
+	assert.Equal(t, "/v1/organisation/accounts?page%5Bnumber%5D=3&page%5Bsize%5D=1", list.Links.Next)
+	assert.Equal(t, "/v1/organisation/accounts?page%5Bnumber%5D=1&page%5Bsize%5D=1", list.Links.Prev)
+}
+
+func TestApiOrganisationAccountListRejectsNegativePage(t *testing.T) {
+	api := NewApi(Options{})
+
+	_, err := api.OrganisationalAccounts.List(ListOptions{PageNumber: -1})
+	assert.EqualError(t, err, "Error - page number must not be negative")
+}
+
+func TestApiRetriedCreateResendsPayload(t *testing.T) {
+	bodies := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		bodies = append(bodies, string(body))
+
+		if len(bodies) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, err = w.Write(body)
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	retry := func(options RetryOptions) (bool, time.Duration) {
+		return options.Attempt < 2, 0
+	}
+	api := NewApi(Options{baseUrl: server.URL, httpClient: server.Client(), retryStrategy: retry})
+
+	newAccount := models.OrganisationAccount{
+		ID:             "48e51a61-29e2-44e6-a97d-4bcf3bda92fc",
+		OrganisationID: "4f8deb65-3755-4252-a495-9660d00c26a5",
+		Attributes: models.OrganisationAccountAttributes{
+			Country: "GB",
+			Name:    []string{"John", "Doe"},
+		},
+	}
+	payload, err := newAccount.Serialize()
+	assert.NoError(t, err)
+
+	account, err := api.OrganisationalAccounts.Create(newAccount)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{string(payload), string(payload)}, bodies)
+	assert.Equal(t, newAccount.ID, account.ID)
+}
+
+func conflictingAccountServer(t *testing.T, existing models.OrganisationAccount) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			w.WriteHeader(http.StatusConflict)
+			_, err := w.Write([]byte(`{"error_message": "Account cannot be created as it violates a duplicate constraint"}`))
+			assert.NoError(t, err)
+			return
+		}
+
+		assert.Equal(t, "/v1/organisation/accounts/"+existing.ID, r.URL.Path)
+		body, err := existing.Serialize()
+		assert.NoError(t, err)
+		_, err = w.Write(body)
+		assert.NoError(t, err)
+	}))
+}
+
+func TestApiIdempotentCreateReturnsMatchingAccount(t *testing.T) {
+	newAccount := models.OrganisationAccount{
+		ID:             "48e51a61-29e2-44e6-a97d-4bcf3bda92fc",
+		OrganisationID: "4f8deb65-3755-4252-a495-9660d00c26a5",
+		Attributes:     models.OrganisationAccountAttributes{Country: "GB", Name: []string{"John", "Doe"}},
+	}
+	existing := newAccount
+	existing.Version = 1
+
+	server := conflictingAccountServer(t, existing)
+	defer server.Close()
+	api := NewApi(Options{baseUrl: server.URL, httpClient: server.Client()})
+
+	_, err := api.OrganisationalAccounts.Create(newAccount)
+	assert.EqualError(t, err, "Error (Status 409) - Account cannot be created as it violates a duplicate constraint")
+
+	account, err := api.OrganisationalAccounts.Create(newAccount, Idempotent())
+	assert.NoError(t, err)
+	assert.Equal(t, newAccount.ID, account.ID)
+	assert.Equal(t, 1, account.Version)
+}
+
+func TestApiIdempotentCreateReportsDifferences(t *testing.T) {
+	newAccount := models.OrganisationAccount{
+		ID:             "48e51a61-29e2-44e6-a97d-4bcf3bda92fc",
+		OrganisationID: "4f8deb65-3755-4252-a495-9660d00c26a5",
+		Attributes:     models.OrganisationAccountAttributes{Country: "GB", Name: []string{"John", "Doe"}},
+	}
+	existing := newAccount
+	existing.Attributes.Country = "FR"
+
+	server := conflictingAccountServer(t, existing)
+	defer server.Close()
+	api := NewApi(Options{baseUrl: server.URL, httpClient: server.Client()})
+
+	_, err := api.OrganisationalAccounts.Create(newAccount, Idempotent())
+
+	var conflict models.AccountConflictError
+	assert.True(t, errors.As(err, &conflict))
+	assert.Equal(t, []string{"attributes.country"}, conflict.Fields)
+	assert.Equal(t, "FR", conflict.Existing.Attributes.Country)
+	assert.EqualError(t, errors.Unwrap(err), "Error (Status 409) - Account cannot be created as it violates a duplicate constraint")
+}
+
+func TestApiMiddlewareAnsweringWithoutRequest(t *testing.T) {
+	unavailable := func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusServiceUnavailable, Header: http.Header{}}, nil
+		})
+	}
+	api, err := NewApiWithOptions(WithBaseURL("http://localhost:1"), WithMiddleware(unavailable),
+		WithRetryStrategy(ConstantBackoff(0, 2)))
+	assert.NoError(t, err)
+
+	_, err = api.OrganisationalAccounts.Fetch("48e51a61-29e2-44e6-a97d-4bcf3bda92fc")
+	assert.True(t, errors.Is(err, models.ErrServer))
+
+	var apiError models.FinanceApiError
+	assert.True(t, errors.As(err, &apiError))
+	assert.Equal(t, "http://localhost:1/v1/organisation/accounts/48e51a61-29e2-44e6-a97d-4bcf3bda92fc", apiError.Url)
+}
+
+func TestApiTransportError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Close()
+
+	api := NewApi(Options{baseUrl: server.URL})
+
+	_, err := api.OrganisationalAccounts.Fetch("48e51a61-29e2-44e6-a97d-4bcf3bda92fc")
+	assert.True(t, errors.Is(err, models.ErrTransport))
+	assert.False(t, errors.Is(err, models.ErrNotFound))
+}
+
+func TestApiResponseReadError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the body is cut short of the length promised
+		w.Header().Set("Content-Length", "100")
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"error_message": `))
+	}))
+	defer server.Close()
+
+	api := NewApi(Options{baseUrl: server.URL, httpClient: server.Client()})
+
+	_, err := api.OrganisationalAccounts.Fetch("48e51a61-29e2-44e6-a97d-4bcf3bda92fc")
+	assert.True(t, errors.Is(err, models.ErrTransport))
+	assert.False(t, errors.Is(err, models.ErrServer))
+	assert.Equal(t, "transport", ErrorClass(0, err))
+}
+
+func TestApiPatchSendsChangedAttributes(t *testing.T) {
+	original := models.OrganisationAccount{
+		ID:         "48e51a61-29e2-44e6-a97d-4bcf3bda92fc",
+		Version:    4,
+		Attributes: models.OrganisationAccountAttributes{Country: "GB", Name: []string{"John", "Doe"}},
+	}
+	updated := original
+	updated.Attributes.Name = []string{"Jane", "Doe"}
+
+	var patch map[string]map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "PATCH", r.Method)
+		assert.Equal(t, "/v1/organisation/accounts/"+original.ID, r.URL.Path)
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.NoError(t, json.Unmarshal(body, &patch))
+
+		saved := updated
+		saved.Version = 5
+		body, err = saved.Serialize()
+		assert.NoError(t, err)
+		_, err = w.Write(body)
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+	api := NewApi(Options{baseUrl: server.URL, httpClient: server.Client()})
+
+	account, err := api.OrganisationalAccounts.Patch(original, updated)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 5, account.Version)
+	assert.Equal(t, float64(4), patch["data"]["version"])
+	assert.Equal(t, map[string]interface{}{"name": []interface{}{"Jane", "Doe"}}, patch["data"]["attributes"])
+}
+
+func TestApiPatchVersionConflict(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		_, err := w.Write([]byte(`{"error_message": "invalid version"}`))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+	api := NewApi(Options{baseUrl: server.URL, httpClient: server.Client()})
+
+	original := models.OrganisationAccount{ID: "48e51a61-29e2-44e6-a97d-4bcf3bda92fc", Version: 4}
+	_, err := api.OrganisationalAccounts.Patch(original, original)
+
+	assert.True(t, errors.Is(err, models.ErrVersionConflict))
+	assert.EqualError(t, errors.Unwrap(err), "Error (Status 409) - invalid version")
+}
+
+func TestApiPatchOtherConflict(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		_, err := w.Write([]byte(`{"error_message": "Account cannot be changed as it violates a duplicate constraint"}`))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+	api := NewApi(Options{baseUrl: server.URL, httpClient: server.Client()})
+
+	original := models.OrganisationAccount{ID: "48e51a61-29e2-44e6-a97d-4bcf3bda92fc", Version: 4}
+	_, err := api.OrganisationalAccounts.Patch(original, original)
+
+	assert.True(t, errors.Is(err, models.ErrConflict))
+	assert.False(t, errors.Is(err, models.ErrVersionConflict))
+	assert.EqualError(t, err, "Error (Status 409) - Account cannot be changed as it violates a duplicate constraint")
+}
+
+func TestApiPatchConflictMentioningVersion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		_, err := w.Write([]byte(`{"error_message": "account_number is used by version 2 of account 26628e05-0bbd-4de2-8da4-7d95bcd15ae0"}`))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+	api := NewApi(Options{baseUrl: server.URL, httpClient: server.Client()})
+
+	original := models.OrganisationAccount{ID: "48e51a61-29e2-44e6-a97d-4bcf3bda92fc", Version: 4}
+	_, err := api.OrganisationalAccounts.Patch(original, original)
+
+	assert.True(t, errors.Is(err, models.ErrConflict))
+	assert.False(t, errors.Is(err, models.ErrVersionConflict))
+	assert.EqualError(t, err, "Error (Status 409) - account_number is used by version 2 of account 26628e05-0bbd-4de2-8da4-7d95bcd15ae0")
+}
+
+func TestApiModifyReappliesMutationOnConflict(t *testing.T) {
+	version := 0
+	patches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PATCH" {
+			patches += 1
+			// someone else changes the account before our first patch lands
+			if patches == 1 {
+				version += 1
+				w.WriteHeader(http.StatusConflict)
+				_, err := w.Write([]byte(`{"error_message": "invalid version"}`))
+				assert.NoError(t, err)
+				return
+			}
+			version += 1
+		}
+
+		account := models.OrganisationAccount{
+			ID:         "48e51a61-29e2-44e6-a97d-4bcf3bda92fc",
+			Version:    version,
+			Attributes: models.OrganisationAccountAttributes{Country: "GB", Name: []string{"John", "Doe"}},
+		}
+		body, err := account.Serialize()
+		assert.NoError(t, err)
+		_, err = w.Write(body)
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+	api := NewApi(Options{baseUrl: server.URL, httpClient: server.Client()})
+
+	seenVersions := []int{}
+	account, err := api.OrganisationalAccounts.Modify("48e51a61-29e2-44e6-a97d-4bcf3bda92fc", 3, func(account *models.OrganisationAccount) error {
+		seenVersions = append(seenVersions, account.Version)
+		account.Attributes.Status = "confirmed"
+		return nil
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []int{0, 1}, seenVersions)
+	assert.Equal(t, 2, account.Version)
+	assert.Equal(t, 2, patches)
+}
+
+func TestApiModifyGivesUpAfterMaxAttempts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PATCH" {
+			w.WriteHeader(http.StatusConflict)
+			_, err := w.Write([]byte(`{"error_message": "invalid version"}`))
+			assert.NoError(t, err)
+			return
+		}
+		_, err := w.Write([]byte(`{"data": {"type": "accounts", "id": "48e51a61-29e2-44e6-a97d-4bcf3bda92fc"}}`))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+	api := NewApi(Options{baseUrl: server.URL, httpClient: server.Client()})
+
+	attempts := 0
+	_, err := api.OrganisationalAccounts.Modify("48e51a61-29e2-44e6-a97d-4bcf3bda92fc", 2, func(account *models.OrganisationAccount) error {
+		attempts += 1
+		return nil
+	})
+
+	assert.True(t, errors.Is(err, models.ErrVersionConflict))
+	assert.Equal(t, 2, attempts)
+}
@@ -0,0 +1,194 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Authenticator adds the credentials to each attempt at a request
+type Authenticator interface {
+	Authenticate(ctx context.Context, req *http.Request) error
+	// Invalidate is called when the api rejected req as unauthorised, so
+	// that the credentials it was sent with aren't used again
+	Invalidate(req *http.Request)
+}
+
+// ClientCredentialsConfig is how to get tokens for the OAuth2
+// client credentials grant, https://tools.ietf.org/html/rfc6749#section-4.4
+type ClientCredentialsConfig struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	// HTTPClient is used to request tokens, http.DefaultClient when nil
+	HTTPClient *http.Client
+	// ExpiryMargin is how long before it expires a token is replaced, 30 seconds when
+	// zero. It's limited to half the token's lifetime so short lived tokens are still reused
+	ExpiryMargin time.Duration
+}
+
+// ClientCredentials authenticates requests with a bearer token from the OAuth2
+// client credentials grant. The token is kept until shortly before it expires,
+// or the api rejects it, and only one new token is requested at a time however
+// many requests are waiting for it
+type ClientCredentials struct {
+	config ClientCredentialsConfig
+	now    func() time.Time
+
+	lock        sync.Mutex
+	accessToken string
+	// expiry is zero for a token that doesn't say when it expires
+	expiry time.Time
+	// refreshing is closed when the token being requested has arrived
+	refreshing chan struct{}
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+func NewClientCredentials(config ClientCredentialsConfig) (*ClientCredentials, error) {
+	tokenUrl, err := url.Parse(config.TokenURL)
+	if err != nil || (tokenUrl.Scheme != "http" && tokenUrl.Scheme != "https") || tokenUrl.Host == "" {
+		return nil, fmt.Errorf("token url %q must be an absolute http or https url", config.TokenURL)
+	}
+	if config.ClientID == "" {
+		return nil, errors.New("client id must not be empty")
+	}
+	if config.ExpiryMargin < 0 {
+		return nil, fmt.Errorf("expiry margin %s must not be negative", config.ExpiryMargin)
+	}
+
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+	if config.ExpiryMargin == 0 {
+		config.ExpiryMargin = 30 * time.Second
+	}
+	return &ClientCredentials{config: config, now: time.Now}, nil
+}
+
+func (credentials *ClientCredentials) Authenticate(ctx context.Context, req *http.Request) error {
+	token, err := credentials.token(ctx)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+func (credentials *ClientCredentials) Invalidate(req *http.Request) {
+	credentials.lock.Lock()
+	defer credentials.lock.Unlock()
+
+	// another request may already have replaced the token
+	if req.Header.Get("Authorization") == "Bearer "+credentials.accessToken {
+		credentials.accessToken = ""
+	}
+}
+
+// token returns the cached token if it's still valid, otherwise it requests a new
+// one or, if another request is already doing that, waits for it to arrive
+func (credentials *ClientCredentials) token(ctx context.Context) (string, error) {
+	for {
+		credentials.lock.Lock()
+		if credentials.accessToken != "" && (credentials.expiry.IsZero() || credentials.now().Before(credentials.expiry)) {
+			token := credentials.accessToken
+			credentials.lock.Unlock()
+			return token, nil
+		}
+
+		if refreshing := credentials.refreshing; refreshing != nil {
+			credentials.lock.Unlock()
+			select {
+			case <-refreshing:
+				// check the cache again, if the request failed we'll try ourselves
+				continue
+			case <-ctx.Done():
+				return "", ctx.Err()
+			}
+		}
+
+		refreshing := make(chan struct{})
+		credentials.refreshing = refreshing
+		credentials.lock.Unlock()
+
+		token, expiry, err := credentials.requestToken(ctx)
+
+		credentials.lock.Lock()
+		if err == nil {
+			credentials.accessToken = token
+			credentials.expiry = expiry
+		}
+		credentials.refreshing = nil
+		close(refreshing)
+		credentials.lock.Unlock()
+
+		return token, err
+	}
+}
+
+func (credentials *ClientCredentials) requestToken(ctx context.Context) (string, time.Time, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(credentials.config.Scopes) > 0 {
+		form.Set("scope", strings.Join(credentials.config.Scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", credentials.config.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(credentials.config.ClientID), url.QueryEscape(credentials.config.ClientSecret))
+
+	requested := credentials.now()
+	resp, err := credentials.config.HTTPClient.Do(req)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("requesting token: %w", err)
+	}
+	defer closeBody(resp)
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("reading token: %w", err)
+	}
+	if !isSuccessResponse(resp) {
+		return "", time.Time{}, fmt.Errorf("token request failed with status %d: %s", resp.StatusCode, body)
+	}
+
+	var token tokenResponse
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", time.Time{}, fmt.Errorf("decoding token: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", time.Time{}, errors.New("token response has no access_token")
+	}
+	if token.TokenType != "" && !strings.EqualFold(token.TokenType, "bearer") {
+		return "", time.Time{}, fmt.Errorf("token type %q isn't supported", token.TokenType)
+	}
+
+	var expiry time.Time
+	if token.ExpiresIn > 0 {
+		lifetime := time.Duration(token.ExpiresIn) * time.Second
+		margin := credentials.config.ExpiryMargin
+		if margin > lifetime/2 {
+			margin = lifetime / 2
+		}
+		// timed from when the token was requested, so the time
+		// taken to respond doesn't make it look valid for longer
+		expiry = requested.Add(lifetime - margin)
+	}
+	return token.AccessToken, expiry, nil
+}
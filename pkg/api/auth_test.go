@@ -0,0 +1,203 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jonorademaker/finance_api_client/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// tokenServer issues the tokens "token-1", "token-2"... counting how many it has issued
+func tokenServer(t *testing.T, expiresIn int, issued *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientID, secret, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "client", clientID)
+		assert.Equal(t, "secret", secret)
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "client_credentials", r.PostForm.Get("grant_type"))
+		assert.Equal(t, "accounts:read accounts:write", r.PostForm.Get("scope"))
+
+		// give concurrent requests time to pile up behind this one
+		time.Sleep(10 * time.Millisecond)
+		token := atomic.AddInt32(issued, 1)
+		w.Header().Set("Content-Type", "application/json")
+		_, err := fmt.Fprintf(w, `{"access_token": "token-%d", "token_type": "bearer", "expires_in": %d}`, token, expiresIn)
+		assert.NoError(t, err)
+	}))
+}
+
+// bearerServer accepts requests with one of the tokens in valid
+func bearerServer(valid ...string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, token := range valid {
+			if r.Header.Get("Authorization") == "Bearer "+token {
+				_, _ = w.Write([]byte("{}"))
+				return
+			}
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+}
+
+func clientCredentialsApi(t *testing.T, tokenUrl string, apiUrl string, expiryMargin time.Duration) baseApi {
+	options, err := NewOptions(WithBaseURL(apiUrl), WithClientCredentials(ClientCredentialsConfig{
+		TokenURL:     tokenUrl,
+		ClientID:     "client",
+		ClientSecret: "secret",
+		Scopes:       []string{"accounts:read", "accounts:write"},
+		ExpiryMargin: expiryMargin,
+	}))
+	assert.NoError(t, err)
+	return createBaseApi(options)
+}
+
+func TestClientCredentialsCachesToken(t *testing.T) {
+	var issued int32
+	tokens := tokenServer(t, 3600, &issued)
+	defer tokens.Close()
+	server := bearerServer("token-1")
+	defer server.Close()
+
+	api := clientCredentialsApi(t, tokens.URL, server.URL, 0)
+	for i := 0; i < 3; i++ {
+		_, err := api.get(context.Background(), "/v1/organisation/accounts", nil)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), issued)
+}
+
+func TestClientCredentialsRefreshesBeforeExpiry(t *testing.T) {
+	var issued int32
+	tokens := tokenServer(t, 120, &issued)
+	defer tokens.Close()
+
+	credentials, err := NewClientCredentials(ClientCredentialsConfig{
+		TokenURL:     tokens.URL,
+		ClientID:     "client",
+		ClientSecret: "secret",
+		Scopes:       []string{"accounts:read", "accounts:write"},
+	})
+	assert.NoError(t, err)
+	now := time.Now()
+	credentials.now = func() time.Time { return now }
+
+	token, err := credentials.token(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "token-1", token)
+
+	// still valid until the 30 second margin before it expires
+	now = now.Add(89 * time.Second)
+	token, err = credentials.token(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "token-1", token)
+
+	now = now.Add(time.Second)
+	token, err = credentials.token(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "token-2", token)
+	assert.Equal(t, int32(2), issued)
+}
+
+func TestClientCredentialsReusesShortLivedToken(t *testing.T) {
+	var issued int32
+	tokens := tokenServer(t, 10, &issued)
+	defer tokens.Close()
+	server := bearerServer("token-1")
+	defer server.Close()
+
+	// the default 30 second margin is longer than the token lasts
+	api := clientCredentialsApi(t, tokens.URL, server.URL, 0)
+	for i := 0; i < 3; i++ {
+		_, err := api.get(context.Background(), "/v1/organisation/accounts", nil)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), issued)
+}
+
+func TestClientCredentialsSingleRefresh(t *testing.T) {
+	var issued int32
+	tokens := tokenServer(t, 3600, &issued)
+	defer tokens.Close()
+	server := bearerServer("token-1")
+	defer server.Close()
+
+	api := clientCredentialsApi(t, tokens.URL, server.URL, 0)
+	var wait sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			_, err := api.get(context.Background(), "/v1/organisation/accounts", nil)
+			assert.NoError(t, err)
+		}()
+	}
+	wait.Wait()
+	assert.Equal(t, int32(1), issued)
+}
+
+func TestClientCredentialsRetriesOnceWhenUnauthorised(t *testing.T) {
+	var issued int32
+	tokens := tokenServer(t, 3600, &issued)
+	defer tokens.Close()
+
+	// token-1 has been revoked
+	server := bearerServer("token-2")
+	defer server.Close()
+
+	api := clientCredentialsApi(t, tokens.URL, server.URL, 0)
+	_, err := api.get(context.Background(), "/v1/organisation/accounts", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), issued)
+
+	rejecting := bearerServer()
+	defer rejecting.Close()
+	api = clientCredentialsApi(t, tokens.URL, rejecting.URL, 0)
+	_, err = api.get(context.Background(), "/v1/organisation/accounts", nil)
+
+	var apiError models.FinanceApiError
+	assert.True(t, errors.As(err, &apiError))
+	assert.Equal(t, http.StatusUnauthorized, apiError.StatusCode)
+	assert.Equal(t, int32(4), issued)
+}
+
+func TestClientCredentialsTokenFailure(t *testing.T) {
+	tokens := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error": "invalid_client"}`))
+	}))
+	defer tokens.Close()
+	server := bearerServer()
+	defer server.Close()
+
+	api := clientCredentialsApi(t, tokens.URL, server.URL, 0)
+	_, err := api.get(context.Background(), "/v1/organisation/accounts", nil)
+	assert.True(t, errors.Is(err, models.ErrTransport))
+	assert.Contains(t, err.Error(), `token request failed with status 400: {"error": "invalid_client"}`)
+}
+
+func TestClientCredentialsInvalidOptions(t *testing.T) {
+	configs := map[string]ClientCredentialsConfig{
+		"relative url":    {TokenURL: "/oauth2/token", ClientID: "client"},
+		"no client id":    {TokenURL: "https://auth.example.com/oauth2/token"},
+		"negative margin": {TokenURL: "https://auth.example.com/oauth2/token", ClientID: "client", ExpiryMargin: -time.Second},
+	}
+	for name, config := range configs {
+		_, err := NewOptions(WithClientCredentials(config))
+		assert.True(t, errors.Is(err, ErrInvalidOption), name)
+	}
+
+	_, err := NewOptions(WithAuthenticator(nil))
+	assert.True(t, errors.Is(err, ErrInvalidOption))
+
+	_, err = NewOptions(WithAuthenticator(&ClientCredentials{}), WithRequestSigner(&HTTPSignatureSigner{}))
+	assert.True(t, errors.Is(err, ErrInvalidOption))
+}
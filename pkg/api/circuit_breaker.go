@@ -0,0 +1,375 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is matched, with errors.Is, by the error returned for a request
+// that wasn't sent because the circuit breaker for its Api's base url is open
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitOpenError is returned for a request that wasn't sent
+// because the circuit breaker for its Api's base url is open
+type CircuitOpenError struct {
+	BaseURL string
+	// RetryIn is how long until the breaker lets a request through to test
+	// whether the api has recovered, 0 when it's already testing it
+	RetryIn time.Duration
+}
+
+func (e CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker for %s is open", e.BaseURL)
+}
+
+func (e CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// CircuitState is the state of the circuit breaker for a base url
+type CircuitState int
+
+const (
+	// CircuitClosed lets every request through, it's the state while the api is healthy
+	CircuitClosed CircuitState = iota
+	// CircuitOpen fails every request without sending it
+	CircuitOpen
+	// CircuitHalfOpen lets a few probe requests through to test whether the api has recovered
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half_open"
+	default:
+		return "closed"
+	}
+}
+
+// CircuitBreakerOptions configures a CircuitBreaker, any left
+// as zero fall back to the defaults described
+type CircuitBreakerOptions struct {
+	// Window is the period requests are counted over, 1 minute by default
+	Window time.Duration
+	// MinRequests is how many requests there have to be in the window
+	// before the breaker can open, 10 by default
+	MinRequests int
+	// FailureRate is the fraction, between 0 and 1, of the requests in the window
+	// that have to fail for the breaker to open, 0.5 by default
+	FailureRate float64
+	// CoolDown is how long the breaker stays open before it lets probe requests
+	// through, 30 seconds by default
+	CoolDown time.Duration
+	// Probes is how many requests are let through while half open, all of them
+	// have to succeed for the breaker to close, 1 by default
+	Probes int
+}
+
+// circuitBuckets is the number of parts the window is split into, requests
+// are forgotten a bucket at a time as they drop out of the window
+const circuitBuckets = 10
+
+type circuitBucket struct {
+	start    time.Time
+	requests int
+	failures int
+}
+
+// circuit is the breaker's state for a single base url
+type circuit struct {
+	state    CircuitState
+	buckets  [circuitBuckets]circuitBucket
+	openedAt time.Time
+	// probing and probeSuccesses count the probes sent and succeeded while half open
+	probing        int
+	probeSuccesses int
+}
+
+// CircuitBreaker stops requests being sent to an api after too many of them have
+// failed, so callers fail fast rather than waiting on retries while the api is down.
+// Once it has cooled down a few probe requests are let through and if they succeed
+// requests are sent again. Attempts that fail with a transport error or server
+// error count as failures. A breaker can be shared by any number of Apis, its
+// state is kept per base url so Apis with the same base url share it
+type CircuitBreaker struct {
+	options   CircuitBreakerOptions
+	now       func() time.Time
+	lock      sync.Mutex
+	circuits  map[string]*circuit
+	listeners []func(baseUrl string, from CircuitState, to CircuitState)
+}
+
+func NewCircuitBreaker(options CircuitBreakerOptions) (*CircuitBreaker, error) {
+	if options.Window < 0 || options.CoolDown < 0 || options.MinRequests < 0 || options.Probes < 0 {
+		return nil, errors.New("circuit breaker options must not be negative")
+	}
+	if options.FailureRate < 0 || options.FailureRate > 1 {
+		return nil, fmt.Errorf("failure rate %v must be between 0 and 1", options.FailureRate)
+	}
+
+	if options.Window == 0 {
+		options.Window = time.Minute
+	}
+	if options.MinRequests == 0 {
+		options.MinRequests = 10
+	}
+	if options.FailureRate == 0 {
+		options.FailureRate = 0.5
+	}
+	if options.CoolDown == 0 {
+		options.CoolDown = 30 * time.Second
+	}
+	if options.Probes == 0 {
+		options.Probes = 1
+	}
+	return &CircuitBreaker{options: options, now: time.Now, circuits: map[string]*circuit{}}, nil
+}
+
+// OnStateChange registers callback to be called whenever the breaker for a base url
+// changes state. It's called after the change, outside of the breaker's lock
+func (breaker *CircuitBreaker) OnStateChange(callback func(baseUrl string, from CircuitState, to CircuitState)) {
+	breaker.lock.Lock()
+	defer breaker.lock.Unlock()
+	breaker.listeners = append(breaker.listeners, callback)
+}
+
+// State is the current state of the breaker for baseUrl
+func (breaker *CircuitBreaker) State(baseUrl string) CircuitState {
+	baseUrl = strings.TrimSuffix(baseUrl, "/")
+	breaker.lock.Lock()
+	defer breaker.lock.Unlock()
+
+	if c, ok := breaker.circuits[baseUrl]; ok {
+		if c.state == CircuitOpen && breaker.now().Sub(c.openedAt) >= breaker.options.CoolDown {
+			return CircuitHalfOpen
+		}
+		return c.state
+	}
+	return CircuitClosed
+}
+
+// stateChange is a change to notify the listeners of once the lock is released
+type stateChange struct {
+	baseUrl     string
+	from, to CircuitState
+}
+
+func (breaker *CircuitBreaker) notify(changes []stateChange) {
+	if len(changes) == 0 {
+		return
+	}
+
+	breaker.lock.Lock()
+	listeners := breaker.listeners
+	breaker.lock.Unlock()
+	for _, change := range changes {
+		for _, listener := range listeners {
+			listener(change.baseUrl, change.from, change.to)
+		}
+	}
+}
+
+func (breaker *CircuitBreaker) circuit(baseUrl string) *circuit {
+	c, ok := breaker.circuits[baseUrl]
+	if !ok {
+		c = &circuit{}
+		breaker.circuits[baseUrl] = c
+	}
+	return c
+}
+
+func (breaker *CircuitBreaker) setState(changes []stateChange, baseUrl string, c *circuit, state CircuitState) []stateChange {
+	if c.state == state {
+		return changes
+	}
+
+	changes = append(changes, stateChange{baseUrl: baseUrl, from: c.state, to: state})
+	c.state = state
+	c.probing = 0
+	c.probeSuccesses = 0
+	switch state {
+	case CircuitOpen:
+		c.openedAt = breaker.now()
+	case CircuitClosed:
+		c.buckets = [circuitBuckets]circuitBucket{}
+	}
+	return changes
+}
+
+// allow decides whether a request to baseUrl can be sent, and whether it's a probe
+func (breaker *CircuitBreaker) allow(baseUrl string) (bool, error) {
+	breaker.lock.Lock()
+	var changes []stateChange
+	defer func() {
+		breaker.lock.Unlock()
+		breaker.notify(changes)
+	}()
+
+	c := breaker.circuit(baseUrl)
+	if c.state == CircuitOpen {
+		retryIn := breaker.options.CoolDown - breaker.now().Sub(c.openedAt)
+		if retryIn > 0 {
+			return false, CircuitOpenError{BaseURL: baseUrl, RetryIn: retryIn}
+		}
+		changes = breaker.setState(changes, baseUrl, c, CircuitHalfOpen)
+	}
+
+	if c.state == CircuitHalfOpen {
+		if c.probing >= breaker.options.Probes {
+			return false, CircuitOpenError{BaseURL: baseUrl}
+		}
+		c.probing += 1
+		return true, nil
+	}
+	return false, nil
+}
+
+// record counts the outcome of a request allow let through. A request the
+// caller gave up on, or that never reached the api, says nothing about the
+// api, so is only released
+func (breaker *CircuitBreaker) record(baseUrl string, probe bool, failed bool, ignored bool) {
+	breaker.lock.Lock()
+	var changes []stateChange
+	defer func() {
+		breaker.lock.Unlock()
+		breaker.notify(changes)
+	}()
+
+	c := breaker.circuit(baseUrl)
+	if probe {
+		// the breaker may have changed state since the probe was let through
+		if c.state != CircuitHalfOpen {
+			return
+		}
+		switch {
+		case ignored:
+			c.probing -= 1
+		case failed:
+			changes = breaker.setState(changes, baseUrl, c, CircuitOpen)
+		default:
+			c.probeSuccesses += 1
+			if c.probeSuccesses >= breaker.options.Probes {
+				changes = breaker.setState(changes, baseUrl, c, CircuitClosed)
+			}
+		}
+		return
+	}
+
+	if ignored || c.state != CircuitClosed {
+		return
+	}
+
+	bucket := breaker.currentBucket(c)
+	bucket.requests += 1
+	if failed {
+		bucket.failures += 1
+	}
+
+	requests, failures := 0, 0
+	for _, b := range c.buckets {
+		if breaker.inWindow(b) {
+			requests += b.requests
+			failures += b.failures
+		}
+	}
+	if requests >= breaker.options.MinRequests && float64(failures) >= breaker.options.FailureRate*float64(requests) {
+		changes = breaker.setState(changes, baseUrl, c, CircuitOpen)
+	}
+}
+
+// currentBucket is the bucket for now, emptied if it was last used a window ago
+func (breaker *CircuitBreaker) currentBucket(c *circuit) *circuitBucket {
+	width := breaker.options.Window / circuitBuckets
+	if width <= 0 {
+		width = 1
+	}
+	start := breaker.now().Truncate(width)
+
+	bucket := &c.buckets[(start.UnixNano()/int64(width))%circuitBuckets]
+	if !bucket.start.Equal(start) {
+		*bucket = circuitBucket{start: start}
+	}
+	return bucket
+}
+
+func (breaker *CircuitBreaker) inWindow(bucket circuitBucket) bool {
+	return bucket.requests > 0 && breaker.now().Sub(bucket.start) < breaker.options.Window
+}
+
+// isCircuitFailure is whether an attempt suggests the api is unavailable, a
+// transport error or server error rather than a problem with the request
+func isCircuitFailure(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode >= 500
+}
+
+type circuitOutcomeKey struct{}
+
+// circuitOutcome is filled in by reportCircuitOutcome once an attempt has been
+// sent to the api, it stays empty for attempts that failed before that, e.g.
+// because a token couldn't be fetched or the request couldn't be signed
+type circuitOutcome struct {
+	sent   bool
+	failed bool
+}
+
+// reportCircuitOutcome wraps the transport that sends requests to the api, so
+// only what happened there is counted by CircuitBreakerMiddleware
+func reportCircuitOutcome(next http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		resp, err := next.RoundTrip(req)
+		if outcome, ok := req.Context().Value(circuitOutcomeKey{}).(*circuitOutcome); ok {
+			outcome.sent = true
+			outcome.failed = isCircuitFailure(resp, err)
+		}
+		return resp, err
+	})
+}
+
+// CircuitBreakerMiddleware fails each attempt at a request fast, with a
+// CircuitOpenError, while breaker is open for baseUrl, the base url of the Api
+// it's used by. Only attempts that were sent to the api count towards opening
+// it, failures of the middlewares after it, such as authenticating, don't
+func CircuitBreakerMiddleware(breaker *CircuitBreaker, baseUrl string) Middleware {
+	return circuitBreakerMiddleware(breaker, baseUrl, nil)
+}
+
+// circuitBreakerMiddleware is CircuitBreakerMiddleware reporting the state
+// of the breaker for baseUrl with metrics, when it isn't nil
+func circuitBreakerMiddleware(breaker *CircuitBreaker, baseUrl string, metrics Metrics) Middleware {
+	// the same base url with or without a trailing slash is the same api
+	baseUrl = strings.TrimSuffix(baseUrl, "/")
+	report := func() {
+		if metrics != nil {
+			metrics.SetCircuitState(baseUrl, breaker.State(baseUrl).String())
+		}
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			probe, err := breaker.allow(baseUrl)
+			report()
+			if err != nil {
+				return nil, err
+			}
+
+			outcome := &circuitOutcome{}
+			req = req.WithContext(context.WithValue(req.Context(), circuitOutcomeKey{}, outcome))
+			resp, err := next.RoundTrip(req)
+			ignored := !outcome.sent || (err != nil && req.Context().Err() != nil)
+			breaker.record(baseUrl, probe, outcome.failed, ignored)
+			report()
+			return resp, err
+		})
+	}
+}
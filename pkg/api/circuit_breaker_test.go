@@ -0,0 +1,260 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jonorademaker/finance_api_client/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// testBreaker is a breaker whose clock only moves when the test moves it
+func testBreaker(t *testing.T, options CircuitBreakerOptions) (*CircuitBreaker, *time.Time) {
+	breaker, err := NewCircuitBreaker(options)
+	assert.NoError(t, err)
+
+	now := time.Date(2021, 6, 14, 19, 51, 27, 0, time.UTC)
+	breaker.now = func() time.Time { return now }
+	return breaker, &now
+}
+
+func TestCircuitBreakerOpensAndRecovers(t *testing.T) {
+	healthy := false
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count += 1
+		if !healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"status": "up"}`))
+	}))
+	defer server.Close()
+	breaker, now := testBreaker(t, CircuitBreakerOptions{MinRequests: 4, FailureRate: 0.5, CoolDown: 10 * time.Second})
+	changes := []string{}
+	breaker.OnStateChange(func(baseUrl string, from CircuitState, to CircuitState) {
+		changes = append(changes, from.String()+" -> "+to.String())
+	})
+
+	metrics := &recordingMetrics{}
+	api, err := NewApiWithOptions(WithBaseURL(server.URL), WithCircuitBreaker(breaker), WithMetrics(metrics),
+		WithRetryStrategy(ConstantBackoff(0, 3)))
+	assert.NoError(t, err)
+
+	// the first attempt of the second call is the fourth failure, opening the
+	// breaker, so its retry fails fast and isn't retried again
+	_, err = api.Health()
+	assert.True(t, errors.Is(err, models.ErrServer))
+	_, err = api.Health()
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+	assert.Equal(t, 4, count)
+	assert.Equal(t, CircuitOpen, breaker.State(server.URL))
+
+	var openError CircuitOpenError
+	assert.True(t, errors.As(err, &openError))
+	assert.Equal(t, server.URL, openError.BaseURL)
+	assert.Equal(t, 10*time.Second, openError.RetryIn)
+	assert.EqualError(t, err, "Error - circuit breaker for "+server.URL+" is open")
+
+	// still cooling down
+	*now = now.Add(9 * time.Second)
+	_, err = api.Health()
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+	assert.Equal(t, 4, count)
+
+	// a failed probe opens it again, so the probe's retry fails fast
+	*now = now.Add(time.Second)
+	assert.Equal(t, CircuitHalfOpen, breaker.State(server.URL))
+	_, err = api.Health()
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+	assert.Equal(t, 5, count)
+	assert.Equal(t, CircuitOpen, breaker.State(server.URL))
+
+	// a successful probe closes it
+	healthy = true
+	*now = now.Add(10 * time.Second)
+	health, err := api.Health()
+	assert.NoError(t, err)
+	assert.True(t, health.IsUp())
+	assert.Equal(t, CircuitClosed, breaker.State(server.URL))
+
+	assert.Equal(t, []string{
+		"closed -> open",
+		"open -> half_open",
+		"half_open -> open",
+		"open -> half_open",
+		"half_open -> closed",
+	}, changes)
+	assert.Equal(t, []string{
+		server.URL + " closed",
+		server.URL + " open",
+		server.URL + " half_open",
+		server.URL + " open",
+		server.URL + " half_open",
+		server.URL + " closed",
+	}, stateChanges(metrics.states))
+}
+
+// stateChanges drops the states that repeat the one before
+func stateChanges(states []string) []string {
+	changes := []string{}
+	for _, state := range states {
+		if len(changes) == 0 || changes[len(changes)-1] != state {
+			changes = append(changes, state)
+		}
+	}
+	return changes
+}
+
+func TestCircuitBreakerSharedByApis(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"status": "up"}`))
+	})
+	first := httptest.NewServer(handler)
+	defer first.Close()
+	second := httptest.NewServer(handler)
+	defer second.Close()
+
+	breaker, _ := testBreaker(t, CircuitBreakerOptions{})
+	firstMetrics, secondMetrics := &recordingMetrics{}, &recordingMetrics{}
+	for i := 0; i < 3; i++ {
+		_, err := NewApiWithOptions(WithBaseURL(second.URL), WithCircuitBreaker(breaker), WithMetrics(secondMetrics))
+		assert.NoError(t, err)
+	}
+	api, err := NewApiWithOptions(WithBaseURL(first.URL), WithCircuitBreaker(breaker), WithMetrics(firstMetrics))
+	assert.NoError(t, err)
+
+	_, err = api.Health()
+	assert.NoError(t, err)
+	assert.Empty(t, breaker.listeners)
+	assert.Equal(t, []string{first.URL + " closed"}, stateChanges(firstMetrics.states))
+	assert.Empty(t, secondMetrics.states)
+}
+
+func TestCircuitBreakerWindow(t *testing.T) {
+	breaker, now := testBreaker(t, CircuitBreakerOptions{Window: time.Minute, MinRequests: 3, FailureRate: 0.6})
+
+	// old failures drop out of the window
+	breaker.record("https://api.example.com", false, true, false)
+	breaker.record("https://api.example.com", false, true, false)
+	*now = now.Add(time.Minute)
+	breaker.record("https://api.example.com", false, true, false)
+	breaker.record("https://api.example.com", false, false, false)
+	assert.Equal(t, CircuitClosed, breaker.State("https://api.example.com"))
+
+	// 2 out of 3 is over the failure rate
+	breaker.record("https://api.example.com", false, true, false)
+	assert.Equal(t, CircuitOpen, breaker.State("https://api.example.com"))
+	// each base url has its own breaker
+	assert.Equal(t, CircuitClosed, breaker.State("https://other.example.com"))
+}
+
+func TestCircuitBreakerProbes(t *testing.T) {
+	breaker, now := testBreaker(t, CircuitBreakerOptions{MinRequests: 1, Probes: 2, CoolDown: time.Second})
+	breaker.record("https://api.example.com", false, true, false)
+	*now = now.Add(time.Second)
+
+	first, err := breaker.allow("https://api.example.com")
+	assert.NoError(t, err)
+	assert.True(t, first)
+	_, err = breaker.allow("https://api.example.com")
+	assert.NoError(t, err)
+	_, err = breaker.allow("https://api.example.com")
+	assert.Equal(t, CircuitOpenError{BaseURL: "https://api.example.com"}, err)
+
+	// a probe the caller gave up on lets another one through
+	breaker.record("https://api.example.com", true, true, true)
+	_, err = breaker.allow("https://api.example.com")
+	assert.NoError(t, err)
+
+	breaker.record("https://api.example.com", true, false, false)
+	assert.Equal(t, CircuitHalfOpen, breaker.State("https://api.example.com"))
+	breaker.record("https://api.example.com", true, false, false)
+	assert.Equal(t, CircuitClosed, breaker.State("https://api.example.com"))
+}
+
+func TestCircuitBreakerIgnoresCancelledRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	breaker, _ := testBreaker(t, CircuitBreakerOptions{MinRequests: 1})
+	api, err := NewApiWithOptions(WithBaseURL(server.URL), WithCircuitBreaker(breaker))
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = api.HealthWithContext(ctx)
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, CircuitClosed, breaker.State(server.URL))
+}
+
+func TestCircuitBreakerPerBaseUrl(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/failing/") {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"status": "up"}`))
+	}))
+	defer server.Close()
+
+	breaker, _ := testBreaker(t, CircuitBreakerOptions{MinRequests: 1})
+	failing, err := NewApiWithOptions(WithBaseURL(server.URL+"/failing/"), WithCircuitBreaker(breaker))
+	assert.NoError(t, err)
+	healthy, err := NewApiWithOptions(WithBaseURL(server.URL+"/healthy"), WithCircuitBreaker(breaker))
+	assert.NoError(t, err)
+
+	// the apis are on the same host, but only the failing one's breaker opens
+	_, err = failing.Health()
+	assert.True(t, errors.Is(err, models.ErrServer))
+	_, err = failing.Health()
+	assert.EqualError(t, err, "Error - circuit breaker for "+server.URL+"/failing is open")
+	_, err = healthy.Health()
+	assert.NoError(t, err)
+
+	assert.Equal(t, CircuitOpen, breaker.State(server.URL+"/failing/"))
+	assert.Equal(t, CircuitClosed, breaker.State(server.URL+"/healthy"))
+}
+
+// unavailableAuthenticator fails as if the token endpoint was down
+type unavailableAuthenticator struct{}
+
+func (unavailableAuthenticator) Authenticate(_ context.Context, _ *http.Request) error {
+	return errors.New("token request failed with status 503")
+}
+
+func (unavailableAuthenticator) Invalidate(_ *http.Request) {}
+
+func TestCircuitBreakerIgnoresFailuresBeforeSending(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("unauthenticated request sent")
+	}))
+	defer server.Close()
+
+	breaker, _ := testBreaker(t, CircuitBreakerOptions{MinRequests: 1})
+	api, err := NewApiWithOptions(WithBaseURL(server.URL), WithCircuitBreaker(breaker),
+		WithAuthenticator(unavailableAuthenticator{}))
+	assert.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		_, err = api.Health()
+		assert.EqualError(t, err, "Error - token request failed with status 503")
+	}
+	assert.Equal(t, CircuitClosed, breaker.State(server.URL))
+}
+
+func TestCircuitBreakerInvalidOptions(t *testing.T) {
+	_, err := NewCircuitBreaker(CircuitBreakerOptions{FailureRate: 1.5})
+	assert.Error(t, err)
+	_, err = NewCircuitBreaker(CircuitBreakerOptions{CoolDown: -time.Second})
+	assert.Error(t, err)
+	_, err = NewOptions(WithCircuitBreaker(nil))
+	assert.True(t, errors.Is(err, ErrInvalidOption))
+}
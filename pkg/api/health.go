@@ -0,0 +1,61 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jonorademaker/finance_api_client/pkg/models"
+)
+
+// Health checks whether the api is up. An api that is down usually responds with
+// an error status, so as well as the error the status in its response is returned
+func (api Api) Health() (models.Health, error) {
+	return api.HealthWithContext(context.Background())
+}
+
+func (api Api) HealthWithContext(ctx context.Context) (_ models.Health, err error) {
+	ctx, end := api.baseApi.startOperation(ctx, "Health")
+	defer func() { end(err) }()
+
+	responseBody, err := api.baseApi.get(ctx, "/v1/health", nil)
+	if err != nil {
+		var apiError models.FinanceApiError
+		if errors.As(err, &apiError) && apiError.StatusCode != 0 {
+			if health, decodeErr := models.DeserializeHealthJson([]byte(apiError.RawBody)); decodeErr == nil {
+				return health, err
+			}
+		}
+		return models.Health{}, err
+	}
+
+	return models.DeserializeHealthJson(responseBody)
+}
+
+// WaitUntilHealthy checks the api's health every interval until it's up, e.g. for
+// waiting on a newly started api. It gives up when ctx is cancelled or expires,
+// returning an error wrapping the context's error
+func (api Api) WaitUntilHealthy(ctx context.Context, interval time.Duration) (err error) {
+	ctx, end := api.baseApi.startOperation(ctx, "WaitUntilHealthy")
+	defer func() { end(err) }()
+
+	if interval <= 0 {
+		return models.FinanceApiError{Err: errors.New("interval must be positive")}
+	}
+
+	for {
+		health, checkErr := api.HealthWithContext(ctx)
+		if checkErr == nil && health.IsUp() {
+			return nil
+		}
+		if checkErr == nil {
+			checkErr = fmt.Errorf("status %q", health.Status)
+		}
+
+		api.baseApi.log(ctx, LevelInfo, "api not healthy", LogKeyError, checkErr, "retry_in", interval)
+		if sleepErr := sleepContext(ctx, interval); sleepErr != nil {
+			return models.FinanceApiError{Err: fmt.Errorf("api not healthy: %w, last check: %s", sleepErr, checkErr)}
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jonorademaker/finance_api_client/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApiHealth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/health", r.URL.Path)
+		_, err := w.Write([]byte(`{"status": "up"}`))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	api := NewApi(Options{baseUrl: server.URL, httpClient: server.Client()})
+	health, err := api.Health()
+	assert.NoError(t, err)
+	assert.Equal(t, models.Health{Status: models.HealthUp}, health)
+	assert.True(t, health.IsUp())
+}
+
+func TestApiHealthDown(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, err := w.Write([]byte(`{"status": "down"}`))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	api := NewApi(Options{baseUrl: server.URL, httpClient: server.Client()})
+	health, err := api.Health()
+	assert.True(t, errors.Is(err, models.ErrServer))
+	assert.Equal(t, models.HealthDown, health.Status)
+	assert.False(t, health.IsUp())
+}
+
+func TestApiWaitUntilHealthy(t *testing.T) {
+	checks := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		checks += 1
+		if checks < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, err := w.Write([]byte(`{"status": "up"}`))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	api := NewApi(Options{baseUrl: server.URL, httpClient: server.Client()})
+	err := api.WaitUntilHealthy(context.Background(), time.Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, checks)
+}
+
+// cancellingLogger cancels the wait once it has been told the api
+// isn't healthy checks times, so the wait ends between two checks
+type cancellingLogger struct {
+	checks int
+	cancel context.CancelFunc
+}
+
+func (l *cancellingLogger) Enabled(_ context.Context, _ Level) bool {
+	return true
+}
+
+func (l *cancellingLogger) Log(_ context.Context, _ Level, msg string, _ ...interface{}) {
+	if msg != "api not healthy" {
+		return
+	}
+	l.checks -= 1
+	if l.checks == 0 {
+		l.cancel()
+	}
+}
+
+func TestApiWaitUntilHealthyGivesUp(t *testing.T) {
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count += 1
+		_, err := w.Write([]byte(`{"status": "down"}`))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	api, err := NewApiWithOptions(WithBaseURL(server.URL), WithLogger(&cancellingLogger{checks: 3, cancel: cancel}))
+	assert.NoError(t, err)
+	err = api.WaitUntilHealthy(ctx, time.Millisecond)
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.EqualError(t, err, `Error - api not healthy: context canceled, last check: status "down"`)
+	assert.Equal(t, 3, count)
+
+	err = api.WaitUntilHealthy(context.Background(), 0)
+	assert.EqualError(t, err, "Error - interval must be positive")
+}
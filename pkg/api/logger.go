@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// Level is the importance of a log entry
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	default:
+		return "LEVEL(" + strconv.Itoa(int(l)) + ")"
+	}
+}
+
+// Log entries use these keys for the details of a request
+const (
+	LogKeyMethod    = "method"
+	LogKeyURL       = "url"
+	LogKeyStatus    = "status"
+	LogKeyAttempt   = "attempt"
+	LogKeyLatency   = "latency"
+	LogKeyRequestID = "request_id"
+	LogKeyBody      = "body"
+	LogKeyError     = "error"
+)
+
+// Logger is a structured logger, it's given a message along with alternating keys
+// and values describing it, e.g. "method", "GET", "status", 200. It can be
+// implemented with whichever logging library the application uses
+type Logger interface {
+	// Enabled is false when entries at level are discarded, so
+	// the work of creating them can be skipped
+	Enabled(ctx context.Context, level Level) bool
+	Log(ctx context.Context, level Level, msg string, keyvals ...interface{})
+}
+
+type stdLogger struct {
+	logger *log.Logger
+	level  Level
+}
+
+// NewStdLogger writes entries at level or above to logger, as a line like
+// "financeapi - INFO response method=GET status=200". The logger itself
+// isn't changed, so it can still be used elsewhere
+func NewStdLogger(logger *log.Logger, level Level) Logger {
+	return stdLogger{logger: logger, level: level}
+}
+
+func (l stdLogger) Enabled(_ context.Context, level Level) bool {
+	return level >= l.level
+}
+
+func (l stdLogger) Log(ctx context.Context, level Level, msg string, keyvals ...interface{}) {
+	if !l.Enabled(ctx, level) {
+		return
+	}
+
+	line := &strings.Builder{}
+	fmt.Fprintf(line, "financeapi - %s %s", level, msg)
+	for i := 0; i < len(keyvals); i += 2 {
+		var value interface{} = "(missing)"
+		if i+1 < len(keyvals) {
+			value = keyvals[i+1]
+		}
+		fmt.Fprintf(line, " %v=%s", keyvals[i], formatLogValue(value))
+	}
+	l.logger.Print(line.String())
+}
+
+func formatLogValue(value interface{}) string {
+	var text string
+	switch value := value.(type) {
+	case fmt.Stringer:
+		text = value.String()
+	case error:
+		text = value.Error()
+	default:
+		text = fmt.Sprint(value)
+	}
+
+	if text == "" || strings.ContainsAny(text, " \"=\n") {
+		return strconv.Quote(text)
+	}
+	return text
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type logEntry struct {
+	level  Level
+	msg    string
+	fields map[string]interface{}
+}
+
+// recordingLogger keeps every entry logged
+type recordingLogger struct {
+	lock    sync.Mutex
+	entries []logEntry
+}
+
+func (l *recordingLogger) Enabled(_ context.Context, _ Level) bool {
+	return true
+}
+
+func (l *recordingLogger) Log(_ context.Context, level Level, msg string, keyvals ...interface{}) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	fields := map[string]interface{}{}
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		fields[keyvals[i].(string)] = keyvals[i+1]
+	}
+	l.entries = append(l.entries, logEntry{level: level, msg: msg, fields: fields})
+}
+
+func TestLoggingMiddleware(t *testing.T) {
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count += 1
+		w.Header().Set("X-Request-Id", fmt.Sprintf("request-%d", count))
+		if count == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"error_message": "try again later"}`))
+			return
+		}
+		_, _ = w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	logger := &recordingLogger{}
+	options, err := NewOptions(WithBaseURL(server.URL), WithLogger(logger), WithRetryStrategy(retryOnce))
+	assert.NoError(t, err)
+	api := createBaseApi(options)
+
+	_, err = api.post(context.Background(), "/v1/organisation/accounts",
+		[]byte(`{"data": {"id": "1", "attributes": {"country": "GB", "iban": "GB33BUKB20201555555555", "name": ["John", "Doe"]}}}`))
+	assert.NoError(t, err)
+
+	assert.Len(t, logger.entries, 4)
+	url := server.URL + "/v1/organisation/accounts"
+	redactedBody := `{"data":{"attributes":{"country":"GB","iban":"[REDACTED]","name":"[REDACTED]"},"id":"1"}}`
+
+	request := logger.entries[0]
+	assert.Equal(t, LevelDebug, request.level)
+	assert.Equal(t, "request", request.msg)
+	assert.Equal(t, "POST", request.fields[LogKeyMethod])
+	assert.Equal(t, url, fmt.Sprint(request.fields[LogKeyURL]))
+	assert.Equal(t, 1, request.fields[LogKeyAttempt])
+	assert.Equal(t, redactedBody, request.fields[LogKeyBody])
+
+	failed := logger.entries[1]
+	assert.Equal(t, LevelWarn, failed.level)
+	assert.Equal(t, "request failed", failed.msg)
+	assert.Equal(t, http.StatusServiceUnavailable, failed.fields[LogKeyStatus])
+	assert.Equal(t, "request-1", failed.fields[LogKeyRequestID])
+	assert.EqualError(t, failed.fields[LogKeyError].(error), "Error (Status 503) - try again later")
+	assert.IsType(t, time.Duration(0), failed.fields[LogKeyLatency])
+
+	assert.Equal(t, 2, logger.entries[2].fields[LogKeyAttempt])
+	response := logger.entries[3]
+	assert.Equal(t, LevelInfo, response.level)
+	assert.Equal(t, "response", response.msg)
+	assert.Equal(t, http.StatusOK, response.fields[LogKeyStatus])
+	assert.Equal(t, "request-2", response.fields[LogKeyRequestID])
+}
+
+func TestLoggingMiddlewareRedactsFilters(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data": []}`))
+	}))
+	defer server.Close()
+
+	logger := &recordingLogger{}
+	api := NewApi(Options{baseUrl: server.URL, logger: logger})
+	_, err := api.OrganisationalAccounts.List(ListOptions{Filter: AccountFilter{Iban: "GB33BUKB20201555555555", Country: "GB"}})
+	assert.NoError(t, err)
+	assert.Equal(t, server.URL+"/v1/organisation/accounts?filter%5Bcountry%5D=GB&filter%5Biban%5D=%5BREDACTED%5D",
+		fmt.Sprint(logger.entries[0].fields[LogKeyURL]))
+
+	logger = &recordingLogger{}
+	api = NewApi(Options{baseUrl: server.URL, logger: logger, logSensitiveData: true})
+	_, err = api.OrganisationalAccounts.List(ListOptions{Filter: AccountFilter{Iban: "GB33BUKB20201555555555"}})
+	assert.NoError(t, err)
+	assert.Contains(t, fmt.Sprint(logger.entries[0].fields[LogKeyURL]), "GB33BUKB20201555555555")
+}
+
+func TestStdLogger(t *testing.T) {
+	output := &bytes.Buffer{}
+	stdLog := log.New(output, "app: ", 0)
+	logger := NewStdLogger(stdLog, LevelInfo)
+
+	logger.Log(context.Background(), LevelDebug, "request", LogKeyMethod, "GET")
+	logger.Log(context.Background(), LevelWarn, "request failed", LogKeyMethod, "GET", LogKeyStatus, 503,
+		LogKeyLatency, 1500*time.Millisecond, LogKeyError, "try again later", "dangling")
+
+	assert.Equal(t, `app: financeapi - WARN request failed method=GET status=503 latency=1.5s error="try again later" dangling=(missing)`,
+		strings.TrimSpace(output.String()))
+	assert.Equal(t, "app: ", stdLog.Prefix())
+	assert.False(t, logger.Enabled(context.Background(), LevelDebug))
+}
+
+func TestApiDoesNotChangeCallersLogger(t *testing.T) {
+	stdLog := log.New(&bytes.Buffer{}, "app: ", log.Lshortfile)
+
+	_, err := NewApiWithOptions(WithLogger(NewStdLogger(stdLog, LevelDebug)))
+	assert.NoError(t, err)
+	assert.Equal(t, "app: ", stdLog.Prefix())
+	assert.Equal(t, log.Lshortfile, stdLog.Flags())
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Metrics records what the client is doing, for alerting on the api's health.
+// The metrics package adapts it to Prometheus
+type Metrics interface {
+	// ObserveRequest records an attempt at a request that has finished. operation
+	// is the Api method making it, see Operation, and statusClass is the class of
+	// its status code, e.g. "2xx", or "error" if it didn't get a response
+	ObserveRequest(operation string, method string, statusClass string, latency time.Duration)
+	// IncRetries counts an attempt at a request that isn't the first
+	IncRetries(operation string, method string)
+	// AddInFlight changes the number of attempts waiting for a response by delta
+	AddInFlight(delta int)
+	// SetCircuitState records the state, "closed", "open" or "half_open", of the
+	// circuit breaker for an Api's base url. It's set around every attempt, so often repeats
+	SetCircuitState(baseUrl string, state string)
+}
+
+// StatusClass is the class of a response's status code, e.g. "4xx"
+// or "error" for a request that didn't get a response
+func StatusClass(statusCode int) string {
+	if statusCode < 100 || statusCode > 599 {
+		return "error"
+	}
+	return strconv.Itoa(statusCode/100) + "xx"
+}
+
+// MetricsMiddleware records each attempt at a request with metrics
+func MetricsMiddleware(metrics Metrics) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			operation := Operation(req.Context())
+			if Attempt(req.Context()) > 1 {
+				metrics.IncRetries(operation, req.Method)
+			}
+
+			metrics.AddInFlight(1)
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+			latency := time.Since(start)
+			metrics.AddInFlight(-1)
+
+			statusCode := 0
+			if err == nil && resp != nil {
+				statusCode = resp.StatusCode
+			}
+			metrics.ObserveRequest(operation, req.Method, StatusClass(statusCode), latency)
+			return resp, err
+		})
+	}
+}
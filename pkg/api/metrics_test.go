@@ -0,0 +1,102 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type observedRequest struct {
+	operation   string
+	method      string
+	statusClass string
+}
+
+type recordingMetrics struct {
+	lock     sync.Mutex
+	requests []observedRequest
+	retries  int
+	inFlight []int
+	states   []string
+}
+
+func (m *recordingMetrics) ObserveRequest(operation string, method string, statusClass string, _ time.Duration) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.requests = append(m.requests, observedRequest{operation, method, statusClass})
+}
+
+func (m *recordingMetrics) IncRetries(_ string, _ string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.retries += 1
+}
+
+func (m *recordingMetrics) AddInFlight(delta int) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.inFlight = append(m.inFlight, delta)
+}
+
+func (m *recordingMetrics) SetCircuitState(baseUrl string, state string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.states = append(m.states, baseUrl+" "+state)
+}
+
+func TestMetricsMiddleware(t *testing.T) {
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count += 1
+		if count == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": {"id": "48e51a61-29e2-44e6-a97d-4bcf3bda92fc"}}`))
+	}))
+	defer server.Close()
+
+	metrics := &recordingMetrics{}
+	api, err := NewApiWithOptions(WithBaseURL(server.URL), WithMetrics(metrics), WithRetryStrategy(ConstantBackoff(0, 2)))
+	assert.NoError(t, err)
+	_, err = api.OrganisationalAccounts.Fetch("48e51a61-29e2-44e6-a97d-4bcf3bda92fc")
+	assert.NoError(t, err)
+
+	assert.Equal(t, []observedRequest{
+		{"OrganisationalAccounts.Fetch", "GET", "4xx"},
+		{"OrganisationalAccounts.Fetch", "GET", "2xx"},
+	}, metrics.requests)
+	assert.Equal(t, 1, metrics.retries)
+	assert.Equal(t, []int{1, -1, 1, -1}, metrics.inFlight)
+}
+
+func TestMetricsRecordTransportErrors(t *testing.T) {
+	failing := func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return nil, errors.New("connection refused")
+		})
+	}
+
+	metrics := &recordingMetrics{}
+	options, err := NewOptions(WithMetrics(metrics), WithMiddleware(failing))
+	assert.NoError(t, err)
+	api := createBaseApi(options)
+	_, err = api.get(context.Background(), "/v1/health", nil)
+	assert.Error(t, err)
+
+	assert.Equal(t, []observedRequest{{"", "GET", "error"}}, metrics.requests)
+	_, err = NewOptions(WithMetrics(nil))
+	assert.True(t, errors.Is(err, ErrInvalidOption))
+}
+
+func TestStatusClass(t *testing.T) {
+	assert.Equal(t, "2xx", StatusClass(204))
+	assert.Equal(t, "5xx", StatusClass(503))
+	assert.Equal(t, "error", StatusClass(0))
+}
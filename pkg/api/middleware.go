@@ -0,0 +1,255 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/jonorademaker/finance_api_client/pkg/models"
+)
+
+// Middleware wraps the http.RoundTripper requests are sent with, e.g. to add
+// headers, inspect responses or record metrics. It's given the next
+// RoundTripper in the chain and returns one that usually calls it
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc lets a function be used as an http.RoundTripper
+type RoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// chain wraps transport in middlewares, the first of them being the outermost
+func chain(transport http.RoundTripper, middlewares ...Middleware) http.RoundTripper {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		if middlewares[i] != nil {
+			transport = middlewares[i](transport)
+		}
+	}
+	return transport
+}
+
+// clientTransport sends requests with client, so its timeout, cookies
+// and redirect policy still apply to requests sent through middleware
+func clientTransport(client *http.Client) http.RoundTripper {
+	return RoundTripperFunc(client.Do)
+}
+
+type attemptKey struct{}
+
+// Attempt is the number of the attempt, starting at 1, at the request that
+// ctx belongs to, 0 if it's not being sent by RetryMiddleware
+func Attempt(ctx context.Context) int {
+	attempt, _ := ctx.Value(attemptKey{}).(int)
+	return attempt
+}
+
+// RetryMiddleware makes another attempt at a request that failed for as long as
+// strategy says to. Each attempt is a copy of the request, with a new body and
+// the current Date. Once it gives up the last response or error is returned
+func RetryMiddleware(strategy RetryStrategy) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			ctx := req.Context()
+			start := time.Now()
+			retries := RetryOptions{Method: req.Method, URL: req.URL}
+			for {
+				attempt, err := attemptRequest(req, retries.Attempt+1)
+				if err != nil {
+					return nil, err
+				}
+
+				resp, err := next.RoundTrip(attempt)
+				retries.Attempt += 1
+				retries.Elapsed = time.Since(start)
+
+				if err == nil && isSuccessResponse(resp) {
+					return resp, nil
+				}
+
+				resp, err = retries.recordFailure(resp, err)
+				// a cancelled or expired context means the caller has given up,
+				// so there is no point asking the strategy for another attempt
+				if ctx.Err() != nil {
+					return resp, err
+				}
+				if !canResend(req) {
+					return resp, err
+				}
+
+				retry, wait := strategy(retries)
+				if !retry {
+					return resp, err
+				}
+
+				closeBody(resp)
+				if err := sleepContext(ctx, wait); err != nil {
+					return nil, err
+				}
+			}
+		})
+	}
+}
+
+// attemptRequest copies req for its attempt'th attempt, every attempt needs
+// its own request as a request's body is consumed by sending it
+func attemptRequest(req *http.Request, attempt int) (*http.Request, error) {
+	attemptReq := req.Clone(context.WithValue(req.Context(), attemptKey{}, attempt))
+	if attempt > 1 {
+		if err := resetBody(attemptReq); err != nil {
+			return nil, err
+		}
+		// required by api docs to be the time the request is sent
+		if attemptReq.Header.Get("Date") != "" {
+			attemptReq.Header.Set("Date", time.Now().Format(time.RFC3339))
+		}
+	}
+	return attemptReq, nil
+}
+
+// resetBody gives req a fresh copy of its body so it can be sent again
+func resetBody(req *http.Request) error {
+	if req.GetBody == nil {
+		return nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return err
+	}
+	req.Body = body
+	return nil
+}
+
+// canResend is false for a request with a body but without GetBody,
+// as there's no way to send the body again
+func canResend(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// requestBody is a copy of req's body, nil when it doesn't have one
+// or it can't be read without consuming it
+func requestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody || !canResend(req) {
+		return nil, nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+// LoggingMiddleware logs each attempt at a request, at LevelDebug with its body,
+// and its response, at LevelInfo or LevelWarn when it failed. When redact is true
+// sensitive attributes, such as names and account numbers, are left out
+func LoggingMiddleware(logger Logger, redact bool) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			ctx := req.Context()
+			logUrl := req.URL
+			if redact {
+				logUrl = models.RedactQuery(req.URL)
+			}
+			fields := []interface{}{LogKeyMethod, req.Method, LogKeyURL, logUrl}
+			if attempt := Attempt(ctx); attempt > 0 {
+				fields = append(fields, LogKeyAttempt, attempt)
+			}
+
+			if logger.Enabled(ctx, LevelDebug) {
+				data, err := requestBody(req)
+				if err != nil {
+					return nil, err
+				}
+				if data == nil {
+					logger.Log(ctx, LevelDebug, "request", fields...)
+				} else {
+					if redact {
+						data = models.RedactJson(data)
+					}
+					logger.Log(ctx, LevelDebug, "request", append(fields, LogKeyBody, string(data))...)
+				}
+			}
+
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+			fields = append(fields, LogKeyLatency, time.Since(start))
+			if resp != nil {
+				fields = append(fields, LogKeyStatus, resp.StatusCode)
+				if requestID := resp.Header.Get("X-Request-Id"); requestID != "" {
+					fields = append(fields, LogKeyRequestID, requestID)
+				}
+			}
+
+			if err == nil && isSuccessResponse(resp) {
+				logger.Log(ctx, LevelInfo, "response", fields...)
+				return resp, nil
+			}
+
+			failure := RetryOptions{Method: req.Method, URL: req.URL}
+			resp, err = failure.recordFailure(resp, err)
+			logger.Log(ctx, LevelWarn, "request failed", append(fields, LogKeyError, failure.failure())...)
+			return resp, err
+		})
+	}
+}
+
+// AuthenticationMiddleware adds authenticator's credentials to each request. If the
+// api rejects them as unauthorised they're invalidated and the request is sent
+// once more with new ones, as they may have been revoked or expired early
+func AuthenticationMiddleware(authenticator Authenticator) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			authenticated := req.Clone(req.Context())
+			if err := authenticator.Authenticate(req.Context(), authenticated); err != nil {
+				return nil, err
+			}
+
+			resp, err := next.RoundTrip(authenticated)
+			if err != nil || resp.StatusCode != http.StatusUnauthorized {
+				return resp, err
+			}
+			if !canResend(req) {
+				return resp, err
+			}
+
+			authenticator.Invalidate(authenticated)
+			closeBody(resp)
+
+			reauthenticated := req.Clone(req.Context())
+			if err := resetBody(reauthenticated); err != nil {
+				return nil, err
+			}
+			if err := authenticator.Authenticate(req.Context(), reauthenticated); err != nil {
+				return nil, err
+			}
+			return next.RoundTrip(reauthenticated)
+		})
+	}
+}
+
+// SigningMiddleware signs each request with signer
+func SigningMiddleware(signer RequestSigner) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if !canResend(req) {
+				return nil, errors.New("can't sign a request whose body can't be copied")
+			}
+			data, err := requestBody(req)
+			if err != nil {
+				return nil, err
+			}
+
+			signed := req.Clone(req.Context())
+			if err := signer.Sign(signed, data); err != nil {
+				return nil, err
+			}
+			return next.RoundTrip(signed)
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func retryOnce(options RetryOptions) (bool, time.Duration) {
+	return options.Attempt < 2, 0
+}
+
+func headerMiddleware(name string, calls *[]string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			*calls = append(*calls, name)
+			req = req.Clone(req.Context())
+			req.Header.Add("X-Middleware", name)
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+func TestMiddlewareChain(t *testing.T) {
+	count := 0
+	received := [][]string{}
+	bodies := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count += 1
+		received = append(received, r.Header.Values("X-Middleware"))
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		bodies = append(bodies, string(body))
+		if count == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("X-Request-Id", "abc")
+		_, err = w.Write([]byte("{}"))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	calls := []string{}
+	attempts := []int{}
+	requestIDs := []string{}
+	inspect := func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			attempts = append(attempts, Attempt(req.Context()))
+			resp, err := next.RoundTrip(req)
+			if err == nil {
+				requestIDs = append(requestIDs, resp.Header.Get("X-Request-Id"))
+			}
+			return resp, err
+		})
+	}
+
+	options, err := NewOptions(
+		WithBaseURL(server.URL),
+		WithRetryStrategy(retryOnce),
+		WithMiddleware(headerMiddleware("first", &calls), inspect),
+		WithMiddleware(headerMiddleware("second", &calls)),
+	)
+	assert.NoError(t, err)
+	api := createBaseApi(options)
+
+	_, err = api.post(context.Background(), "/v1/organisation/accounts", []byte(`{"data": {}}`))
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"first", "second", "first", "second"}, calls)
+	assert.Equal(t, []int{1, 2}, attempts)
+	assert.Equal(t, []string{"", "abc"}, requestIDs)
+	assert.Equal(t, [][]string{{"first", "second"}, {"first", "second"}}, received)
+	assert.Equal(t, []string{`{"data": {}}`, `{"data": {}}`}, bodies)
+}
+
+func TestMiddlewareErrorsAreTransportErrors(t *testing.T) {
+	failing := func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return nil, errors.New("refused by middleware")
+		})
+	}
+
+	api, err := NewApiWithOptions(WithMiddleware(failing))
+	assert.NoError(t, err)
+	_, err = api.OrganisationalAccounts.Fetch("48e51a61-29e2-44e6-a97d-4bcf3bda92fc")
+	assert.EqualError(t, err, "Error - refused by middleware")
+
+	_, err = NewOptions(WithMiddleware(nil))
+	assert.True(t, errors.Is(err, ErrInvalidOption))
+}
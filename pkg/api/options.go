@@ -0,0 +1,259 @@
+package api
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrInvalidOption is wrapped by every error returned while applying an Option
+// so callers can tell configuration mistakes apart from api failures
+var ErrInvalidOption = errors.New("invalid option")
+
+// Option configures the Options used to create an Api, returning an error
+// wrapping ErrInvalidOption when it is given a value that can't be used
+type Option func(options *Options) error
+
+// WithBaseURL sets the scheme and host the api is reached on, e.g. https://api.form3.tech
+func WithBaseURL(baseUrl string) Option {
+	return func(options *Options) error {
+		parsedUrl, err := url.Parse(baseUrl)
+		if err != nil {
+			return fmt.Errorf("%w: base url %q: %v", ErrInvalidOption, baseUrl, err)
+		}
+		if parsedUrl.Scheme != "http" && parsedUrl.Scheme != "https" {
+			return fmt.Errorf("%w: base url %q must use http or https", ErrInvalidOption, baseUrl)
+		}
+		if parsedUrl.Host == "" {
+			return fmt.Errorf("%w: base url %q has no host", ErrInvalidOption, baseUrl)
+		}
+		if parsedUrl.RawQuery != "" || parsedUrl.Fragment != "" {
+			return fmt.Errorf("%w: base url %q must not have a query or fragment", ErrInvalidOption, baseUrl)
+		}
+
+		options.baseUrl = baseUrl
+		return nil
+	}
+}
+
+// WithHTTPClient sets the client requests are sent with. The client is never
+// modified, if WithTimeout is also given a copy of the client is used instead
+func WithHTTPClient(client *http.Client) Option {
+	return func(options *Options) error {
+		if client == nil {
+			return fmt.Errorf("%w: http client must not be nil", ErrInvalidOption)
+		}
+
+		options.httpClient = client
+		return nil
+	}
+}
+
+// WithTimeout sets the time limit for each request made, including
+// reading the response body
+func WithTimeout(timeout time.Duration) Option {
+	return func(options *Options) error {
+		if timeout < time.Millisecond {
+			return fmt.Errorf("%w: timeout %s must be at least 1ms", ErrInvalidOption, timeout)
+		}
+
+		options.timeoutInMilliseconds = int(timeout / time.Millisecond)
+		return nil
+	}
+}
+
+// WithLogger sets the logger requests and failures are written to, see
+// NewStdLogger for using a *log.Logger. Sensitive attributes of accounts
+// are redacted unless WithSensitiveDataLogging is also given
+func WithLogger(logger Logger) Option {
+	return func(options *Options) error {
+		if logger == nil {
+			return fmt.Errorf("%w: logger must not be nil", ErrInvalidOption)
+		}
+
+		options.logger = logger
+		return nil
+	}
+}
+
+// WithSensitiveDataLogging stops the logger redacting sensitive attributes of accounts,
+// such as names and account numbers, from what it logs, e.g. while debugging locally
+func WithSensitiveDataLogging() Option {
+	return func(options *Options) error {
+		options.logSensitiveData = true
+		return nil
+	}
+}
+
+// WithRetryStrategy sets the strategy consulted whenever a request fails
+func WithRetryStrategy(strategy RetryStrategy) Option {
+	return func(options *Options) error {
+		if strategy == nil {
+			return fmt.Errorf("%w: retry strategy must not be nil", ErrInvalidOption)
+		}
+
+		options.retryStrategy = strategy
+		return nil
+	}
+}
+
+// WithRequestSigner sets the signer that authenticates every request sent
+func WithRequestSigner(signer RequestSigner) Option {
+	return func(options *Options) error {
+		if signer == nil {
+			return fmt.Errorf("%w: request signer must not be nil", ErrInvalidOption)
+		}
+
+		options.signer = signer
+		return nil
+	}
+}
+
+// WithSigningKey signs every request with the RSA key registered with the api
+// as keyID, see HTTPSignatureSigner
+func WithSigningKey(keyID string, key crypto.Signer) Option {
+	return func(options *Options) error {
+		signer, err := NewHTTPSignatureSigner(keyID, key)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidOption, err)
+		}
+
+		options.signer = signer
+		return nil
+	}
+}
+
+// WithAuthenticator sets the authenticator that adds credentials to every request sent
+func WithAuthenticator(authenticator Authenticator) Option {
+	return func(options *Options) error {
+		if authenticator == nil {
+			return fmt.Errorf("%w: authenticator must not be nil", ErrInvalidOption)
+		}
+
+		options.authenticator = authenticator
+		return nil
+	}
+}
+
+// WithClientCredentials authenticates every request with a bearer token from
+// the OAuth2 client credentials grant, see ClientCredentials
+func WithClientCredentials(config ClientCredentialsConfig) Option {
+	return func(options *Options) error {
+		credentials, err := NewClientCredentials(config)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidOption, err)
+		}
+
+		options.authenticator = credentials
+		return nil
+	}
+}
+
+// WithMiddleware adds middlewares to the chain every attempt at a request is sent
+// through, the first given being the outermost. They run inside the retries and
+// logging, and before the request is authenticated or signed
+func WithMiddleware(middlewares ...Middleware) Option {
+	return func(options *Options) error {
+		for _, middleware := range middlewares {
+			if middleware == nil {
+				return fmt.Errorf("%w: middleware must not be nil", ErrInvalidOption)
+			}
+		}
+
+		options.middlewares = append(options.middlewares, middlewares...)
+		return nil
+	}
+}
+
+// WithTracerProvider enables OpenTelemetry tracing, with a span for each call to
+// an Api method and a child span for each attempt at each request it makes. The
+// trace is propagated to the api with a W3C traceparent header
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(options *Options) error {
+		if provider == nil {
+			return fmt.Errorf("%w: tracer provider must not be nil", ErrInvalidOption)
+		}
+
+		options.tracerProvider = provider
+		return nil
+	}
+}
+
+// WithMetrics records every attempt at a request, and the state of the
+// circuit breaker, with metrics
+func WithMetrics(metrics Metrics) Option {
+	return func(options *Options) error {
+		if metrics == nil {
+			return fmt.Errorf("%w: metrics must not be nil", ErrInvalidOption)
+		}
+
+		options.metrics = metrics
+		return nil
+	}
+}
+
+// WithCircuitBreaker fails requests fast, without retrying them, while breaker
+// is open for the Api's base url. The breaker may be shared with other Apis, with
+// WithMetrics the state is reported for this Api's base url
+func WithCircuitBreaker(breaker *CircuitBreaker) Option {
+	return func(options *Options) error {
+		if breaker == nil {
+			return fmt.Errorf("%w: circuit breaker must not be nil", ErrInvalidOption)
+		}
+
+		options.circuitBreaker = breaker
+		return nil
+	}
+}
+
+// NewOptions applies opts in order and checks the result is consistent,
+// any option left unset falls back to the same defaults as NewApi
+func NewOptions(opts ...Option) (Options, error) {
+	options := Options{}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(&options); err != nil {
+			return Options{}, err
+		}
+	}
+
+	if err := options.validate(); err != nil {
+		return Options{}, err
+	}
+	return options, nil
+}
+
+func (options Options) validate() error {
+	if options.httpClient != nil && options.httpClient.Timeout != 0 && options.timeoutInMilliseconds > 0 {
+		timeout := time.Duration(options.timeoutInMilliseconds) * time.Millisecond
+		if options.httpClient.Timeout != timeout {
+			return fmt.Errorf("%w: timeout %s conflicts with the http client's own timeout of %s",
+				ErrInvalidOption, timeout, options.httpClient.Timeout)
+		}
+	}
+
+	// both set the Authorization header
+	if options.signer != nil && options.authenticator != nil {
+		return fmt.Errorf("%w: requests can't be both signed and authenticated", ErrInvalidOption)
+	}
+
+	return nil
+}
+
+// NewApiWithOptions creates an Api configured by opts, failing if any of
+// them are invalid
+func NewApiWithOptions(opts ...Option) (Api, error) {
+	options, err := NewOptions(opts...)
+	if err != nil {
+		return Api{}, err
+	}
+
+	return NewApi(options), nil
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptionsApplied(t *testing.T) {
+	client := &http.Client{}
+	retry := ConstantBackoff(time.Second, 3)
+
+	options, err := NewOptions(
+		WithBaseURL("https://api.example.com"),
+		WithHTTPClient(client),
+		WithTimeout(1500*time.Millisecond),
+		WithRetryStrategy(retry),
+	)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "https://api.example.com", options.baseUrl)
+	assert.Equal(t, client, options.httpClient)
+	assert.Equal(t, 1500, options.timeoutInMilliseconds)
+	assert.NotNil(t, options.retryStrategy)
+}
+
+func TestOptionsInvalidValues(t *testing.T) {
+	tests := map[string]Option{
+		"no scheme":          WithBaseURL("api.example.com"),
+		"ftp scheme":         WithBaseURL("ftp://api.example.com"),
+		"query string":       WithBaseURL("https://api.example.com?debug=1"),
+		"nil client":         WithHTTPClient(nil),
+		"negative timeout":   WithTimeout(-time.Second),
+		"nil logger":         WithLogger(nil),
+		"nil retry strategy": WithRetryStrategy(nil),
+	}
+
+	for name, opt := range tests {
+		_, err := NewOptions(opt)
+		assert.True(t, errors.Is(err, ErrInvalidOption), name)
+	}
+}
+
+func TestOptionsConflictingTimeouts(t *testing.T) {
+	client := &http.Client{Timeout: time.Second}
+
+	_, err := NewOptions(WithHTTPClient(client), WithTimeout(2*time.Second))
+	assert.True(t, errors.Is(err, ErrInvalidOption))
+
+	_, err = NewOptions(WithHTTPClient(client), WithTimeout(time.Second))
+	assert.NoError(t, err)
+}
+
+func TestTimeoutDoesNotModifyCallersClient(t *testing.T) {
+	client := &http.Client{}
+
+	api, err := NewApiWithOptions(WithHTTPClient(client), WithTimeout(time.Second))
+	assert.NoError(t, err)
+
+	assert.Equal(t, time.Duration(0), client.Timeout)
+	assert.Equal(t, time.Second, api.OrganisationalAccounts.baseApi.client.Timeout)
+}
@@ -1,8 +1,12 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/jonorademaker/finance_api_client/pkg/models"
 )
@@ -11,23 +15,120 @@ type organisationalAccounts struct {
 	baseApi baseApi
 }
 
-func (accountsApi *organisationalAccounts) Create(account models.OrganisationAccount) (models.OrganisationAccount, error) {
+// AccountFilter narrows down the accounts returned by List,
+// only the fields that are set are sent to the api
+type AccountFilter struct {
+	BankID        string
+	BankIDCode    string
+	AccountNumber string
+	Iban          string
+	CustomerID    string
+	Country       string
+}
+
+// ListOptions selects which page of accounts List returns. Pages are numbered
+// from 0 and the api's own default page size is used when PageSize is 0
+type ListOptions struct {
+	PageNumber int
+	PageSize   int
+	Filter     AccountFilter
+}
+
+// CreateOption changes how a single call to Create behaves
+type CreateOption func(options *createOptions)
+
+type createOptions struct {
+	idempotent     bool
+	skipValidation bool
+}
+
+// SkipValidation sends the account to the api without checking it with
+// models.OrganisationAccount.Validate first, leaving the api to reject it
+func SkipValidation() CreateOption {
+	return func(options *createOptions) {
+		options.skipValidation = true
+	}
+}
+
+// Idempotent makes Create safe to repeat after a timeout or failure where it isn't
+// known whether the account was created. If the api reports the account already
+// exists it's fetched and returned when it matches the account being created,
+// otherwise a models.AccountConflictError listing the differences is returned
+func Idempotent() CreateOption {
+	return func(options *createOptions) {
+		options.idempotent = true
+	}
+}
+
+func (accountsApi *organisationalAccounts) Create(account models.OrganisationAccount, opts ...CreateOption) (models.OrganisationAccount, error) {
+	return accountsApi.CreateWithContext(context.Background(), account, opts...)
+}
+
+// CreateWithContext behaves like Create, aborting the request and any
+// pending retries as soon as ctx is cancelled or its deadline expires
+func (accountsApi *organisationalAccounts) CreateWithContext(ctx context.Context, account models.OrganisationAccount, opts ...CreateOption) (_ models.OrganisationAccount, err error) {
+	ctx, end := accountsApi.baseApi.startOperation(ctx, "OrganisationalAccounts.Create")
+	defer func() { end(err) }()
+
+	options := createOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if !options.skipValidation {
+		if err = account.Validate(); err != nil {
+			return models.OrganisationAccount{}, err
+		}
+	}
+
 	payload, err := account.Serialize()
 	if err != nil {
 		return models.OrganisationAccount{}, err
 	}
 
-	responseBody, err := accountsApi.baseApi.post("/v1/organisation/accounts", payload)
+	responseBody, err := accountsApi.baseApi.post(ctx, "/v1/organisation/accounts", payload)
 	if err != nil {
+		if options.idempotent && errors.Is(err, models.ErrConflict) {
+			return accountsApi.reconcileConflict(ctx, account, err)
+		}
 		return models.OrganisationAccount{}, err
 	}
 
 	return models.DeserializeAccountJson(responseBody)
 }
 
+// reconcileConflict decides whether the account that already exists
+// is the one we were trying to create
+func (accountsApi *organisationalAccounts) reconcileConflict(ctx context.Context, account models.OrganisationAccount, conflict error) (models.OrganisationAccount, error) {
+	existing, err := accountsApi.FetchWithContext(ctx, account.ID)
+	if err != nil {
+		return models.OrganisationAccount{}, err
+	}
+
+	differences := account.Diff(existing)
+	if len(differences) > 0 {
+		return models.OrganisationAccount{}, models.AccountConflictError{
+			ID:       account.ID,
+			Fields:   differences,
+			Existing: existing,
+			Err:      conflict,
+		}
+	}
+	return existing, nil
+}
+
 func (accountsApi *organisationalAccounts) Fetch(id string) (models.OrganisationAccount, error) {
+	return accountsApi.FetchWithContext(context.Background(), id)
+}
+
+// FetchWithContext behaves like Fetch, aborting the request and any
+// pending retries as soon as ctx is cancelled or its deadline expires
+func (accountsApi *organisationalAccounts) FetchWithContext(ctx context.Context, id string) (_ models.OrganisationAccount, err error) {
+	ctx, end := accountsApi.baseApi.startOperation(ctx, "OrganisationalAccounts.Fetch")
+	defer func() { end(err) }()
+
 	resourceUrl := fmt.Sprintf("/v1/organisation/accounts/%s", id)
-	responseBody, err := accountsApi.baseApi.get(resourceUrl)
+	responseBody, err := accountsApi.baseApi.get(ctx, resourceUrl, nil)
 	if err != nil {
 		return models.OrganisationAccount{}, err
 	}
@@ -35,9 +136,164 @@ func (accountsApi *organisationalAccounts) Fetch(id string) (models.Organisation
 	return models.DeserializeAccountJson(responseBody)
 }
 
+// Patch changes original, an account previously fetched or created, so it matches
+// updated. Only the changed attributes are sent and, if the account has been
+// modified since original was fetched, a models.VersionConflictError is returned
+func (accountsApi *organisationalAccounts) Patch(original models.OrganisationAccount, updated models.OrganisationAccount) (models.OrganisationAccount, error) {
+	return accountsApi.PatchWithContext(context.Background(), original, updated)
+}
+
+// PatchWithContext behaves like Patch, aborting the request and any
+// pending retries as soon as ctx is cancelled or its deadline expires
+func (accountsApi *organisationalAccounts) PatchWithContext(ctx context.Context, original models.OrganisationAccount, updated models.OrganisationAccount) (_ models.OrganisationAccount, err error) {
+	ctx, end := accountsApi.baseApi.startOperation(ctx, "OrganisationalAccounts.Patch")
+	defer func() { end(err) }()
+
+	payload, err := updated.SerializePatch(original)
+	if err != nil {
+		return models.OrganisationAccount{}, err
+	}
+
+	resourceUrl := fmt.Sprintf("/v1/organisation/accounts/%s", original.ID)
+	responseBody, err := accountsApi.baseApi.patch(ctx, resourceUrl, payload)
+	if err != nil {
+		if isVersionMismatch(err) {
+			return models.OrganisationAccount{}, models.VersionConflictError{ID: original.ID, Version: original.Version, Err: err}
+		}
+		return models.OrganisationAccount{}, err
+	}
+
+	return models.DeserializeAccountJson(responseBody)
+}
+
+// Modify fetches the account, applies mutate to it and patches the result. When
+// the account is changed by someone else in the meantime it's fetched again and
+// mutate is reapplied, up to maxAttempts times in total
+func (accountsApi *organisationalAccounts) Modify(id string, maxAttempts int, mutate func(account *models.OrganisationAccount) error) (models.OrganisationAccount, error) {
+	return accountsApi.ModifyWithContext(context.Background(), id, maxAttempts, mutate)
+}
+
+// ModifyWithContext behaves like Modify, aborting the requests as soon
+// as ctx is cancelled or its deadline expires
+func (accountsApi *organisationalAccounts) ModifyWithContext(ctx context.Context, id string, maxAttempts int, mutate func(account *models.OrganisationAccount) error) (_ models.OrganisationAccount, err error) {
+	ctx, end := accountsApi.baseApi.startOperation(ctx, "OrganisationalAccounts.Modify")
+	defer func() { end(err) }()
+
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		resourceUrl := fmt.Sprintf("/v1/organisation/accounts/%s", id)
+		var responseBody []byte
+		responseBody, err = accountsApi.baseApi.get(ctx, resourceUrl, nil)
+		if err != nil {
+			return models.OrganisationAccount{}, err
+		}
+
+		// decoded twice so mutate can't change original through a shared slice
+		var original, updated models.OrganisationAccount
+		if original, err = models.DeserializeAccountJson(responseBody); err != nil {
+			return models.OrganisationAccount{}, err
+		}
+		if updated, err = models.DeserializeAccountJson(responseBody); err != nil {
+			return models.OrganisationAccount{}, err
+		}
+		if err = mutate(&updated); err != nil {
+			return models.OrganisationAccount{}, err
+		}
+
+		var account models.OrganisationAccount
+		account, err = accountsApi.PatchWithContext(ctx, original, updated)
+		if !errors.Is(err, models.ErrVersionConflict) {
+			return account, err
+		}
+	}
+
+	if err == nil {
+		err = models.FinanceApiError{Err: errors.New("max attempts must be at least 1")}
+	}
+	return models.OrganisationAccount{}, err
+}
+
+func (accountsApi *organisationalAccounts) List(options ListOptions) (models.OrganisationAccountList, error) {
+	return accountsApi.ListWithContext(context.Background(), options)
+}
+
+// ListWithContext behaves like List, aborting the request and any
+// pending retries as soon as ctx is cancelled or its deadline expires
+func (accountsApi *organisationalAccounts) ListWithContext(ctx context.Context, options ListOptions) (_ models.OrganisationAccountList, err error) {
+	ctx, end := accountsApi.baseApi.startOperation(ctx, "OrganisationalAccounts.List")
+	defer func() { end(err) }()
+
+	queryString, err := options.queryString()
+	if err != nil {
+		return models.OrganisationAccountList{}, err
+	}
+
+	responseBody, err := accountsApi.baseApi.get(ctx, "/v1/organisation/accounts", queryString)
+	if err != nil {
+		return models.OrganisationAccountList{}, err
+	}
+
+	return models.DeserializeAccountListJson(responseBody)
+}
+
 func (accountsApi *organisationalAccounts) Delete(id string, version int) error {
+	return accountsApi.DeleteWithContext(context.Background(), id, version)
+}
+
+// DeleteWithContext behaves like Delete, aborting the request and any
+// pending retries as soon as ctx is cancelled or its deadline expires
+func (accountsApi *organisationalAccounts) DeleteWithContext(ctx context.Context, id string, version int) (err error) {
+	ctx, end := accountsApi.baseApi.startOperation(ctx, "OrganisationalAccounts.Delete")
+	defer func() { end(err) }()
+
 	resourceUrl := fmt.Sprintf("/v1/organisation/accounts/%s", id)
 	queryString := map[string][]string{"version": {strconv.Itoa(version)}}
 
-	return accountsApi.baseApi.delete(resourceUrl, queryString)
+	return accountsApi.baseApi.delete(ctx, resourceUrl, queryString)
+}
+
+func (options ListOptions) queryString() (map[string][]string, error) {
+	if options.PageNumber < 0 {
+		return nil, models.FinanceApiError{Err: errors.New("page number must not be negative")}
+	}
+	if options.PageSize < 0 {
+		return nil, models.FinanceApiError{Err: errors.New("page size must not be negative")}
+	}
+
+	queryString := map[string][]string{}
+	if options.PageNumber > 0 {
+		queryString["page[number]"] = []string{strconv.Itoa(options.PageNumber)}
+	}
+	if options.PageSize > 0 {
+		queryString["page[size]"] = []string{strconv.Itoa(options.PageSize)}
+	}
+
+	filters := map[string]string{
+		"bank_id":        options.Filter.BankID,
+		"bank_id_code":   options.Filter.BankIDCode,
+		"account_number": options.Filter.AccountNumber,
+		"iban":           options.Filter.Iban,
+		"customer_id":    options.Filter.CustomerID,
+		"country":        options.Filter.Country,
+	}
+	for name, value := range filters {
+		if value != "" {
+			queryString[fmt.Sprintf("filter[%s]", name)] = []string{value}
+		}
+	}
+
+	return queryString, nil
+}
+
+// versionMismatchMessage is the error_message the api responds with, along with
+// a 409, when the version of a change isn't the account's current one
+const versionMismatchMessage = "invalid version"
+
+// isVersionMismatch is whether err is the api rejecting a change because the
+// version sent isn't the account's current one, rather than any other conflict
+func isVersionMismatch(err error) bool {
+	var apiError models.FinanceApiError
+	if !errors.As(err, &apiError) || apiError.StatusCode != http.StatusConflict || apiError.Err == nil {
+		return false
+	}
+	return strings.EqualFold(strings.TrimSpace(apiError.Err.Error()), versionMismatchMessage)
 }
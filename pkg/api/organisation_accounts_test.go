@@ -3,9 +3,12 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"log"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jonorademaker/finance_api_client/pkg/models"
@@ -16,7 +19,7 @@ func createTestApi() Api {
 	testUrl := os.Getenv("TEST_URL")
 	logger := log.Default()
 	logger.SetFlags(log.Lshortfile)
-	options := Options{logger: logger}
+	options := Options{logger: NewStdLogger(logger, LevelDebug)}
 	if testUrl != "" {
 		options.baseUrl = testUrl
 	}
@@ -24,6 +27,18 @@ func createTestApi() Api {
 	return NewApi(options)
 }
 
+// TestMain waits for the api, which may still be starting up when the tests are run
+func TestMain(m *testing.M) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	err := createTestApi().WaitUntilHealthy(ctx, time.Second)
+	cancel()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	os.Exit(m.Run())
+}
+
 func createAccount(t *testing.T) models.OrganisationAccount {
 	accountId, err := uuid.NewUUID()
 	assert.NoError(t, err)
@@ -76,11 +91,23 @@ func TestApiOrganisationAccountCreateFail(t *testing.T) {
 	newAccount.OrganisationID = ""
 	api := createTestApi()
 
-	_, err := api.OrganisationalAccounts.Create(newAccount)
+	_, err := api.OrganisationalAccounts.Create(newAccount, SkipValidation())
 
 	assert.EqualError(t, err, "Error (Status 400) - validation failure list:\nid in body is required\norganisation_id in body is required")
 }
 
+func TestApiOrganisationAccountCreateFailsValidation(t *testing.T) {
+	newAccount := createAccount(t)
+	newAccount.ID = ""
+	newAccount.OrganisationID = ""
+	api := createTestApi()
+
+	_, err := api.OrganisationalAccounts.Create(newAccount)
+
+	assert.EqualError(t, err, "Error - validation failure list:\nid in body is required\norganisation_id in body is required")
+	assert.True(t, errors.Is(err, models.ErrValidation))
+}
+
 func TestApiOrganisationAccountFetch(t *testing.T) {
 	newAccount := createAccount(t)
 	api := createTestApi()
@@ -115,6 +142,7 @@ func TestApiOrganisationAccountFetchFail(t *testing.T) {
 	_, err := api.OrganisationalAccounts.Fetch("fab96ee7-edb3-4319-b486-a1233ad52960")
 
 	assert.EqualError(t, err, "Error (Status 404) - record fab96ee7-edb3-4319-b486-a1233ad52960 does not exist")
+	assert.True(t, errors.Is(err, models.ErrNotFound))
 }
 
 func TestApiOrganisationAccountDelete(t *testing.T) {
@@ -136,3 +164,17 @@ func TestApiOrganisationAccountDeleteFail(t *testing.T) {
 	err := api.OrganisationalAccounts.Delete("adsfas7fasfdsdaf", 234)
 	assert.EqualError(t, err, "Error (Status 400) - id is not a valid uuid")
 }
+
+func TestApiOrganisationAccountList(t *testing.T) {
+	newAccount := createAccount(t)
+	api := createTestApi()
+	_, err := api.OrganisationalAccounts.Create(newAccount)
+	assert.NoError(t, err)
+
+	list, err := api.OrganisationalAccounts.List(ListOptions{PageSize: 1})
+	assert.NoError(t, err)
+
+	assert.Len(t, list.Accounts, 1)
+	assert.NotEmpty(t, list.Links.First)
+	assert.NotEmpty(t, list.Links.Last)
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// RequestSigner adds the headers authenticating a request just before each
+// attempt is sent. body is the request's body, nil when it doesn't have one
+type RequestSigner interface {
+	Sign(req *http.Request, body []byte) error
+}
+
+// HTTPSignatureSigner signs requests as described in the HTTP Signatures draft,
+// https://tools.ietf.org/html/draft-cavage-http-signatures-10, using rsa-sha256
+// over (request-target), host and date, plus digest and content-type when the
+// request has a body, as the api requires
+// https://api-docs.form3.tech/api.html#authentication-message-signing
+type HTTPSignatureSigner struct {
+	keyID string
+	key   crypto.Signer
+}
+
+// NewHTTPSignatureSigner creates a signer for the key registered with the api as
+// keyID. The key has to be an RSA key, e.g. an *rsa.PrivateKey or one held in an HSM
+func NewHTTPSignatureSigner(keyID string, key crypto.Signer) (*HTTPSignatureSigner, error) {
+	if keyID == "" {
+		return nil, errors.New("key id must not be empty")
+	}
+	// a nil pointer to a key, e.g. a (*rsa.PrivateKey)(nil), isn't a nil
+	// interface but would panic once used
+	if key == nil || (reflect.ValueOf(key).Kind() == reflect.Ptr && reflect.ValueOf(key).IsNil()) {
+		return nil, errors.New("signing key must not be nil")
+	}
+	if _, ok := key.Public().(*rsa.PublicKey); !ok {
+		return nil, fmt.Errorf("signing key must be an RSA key, got %T", key.Public())
+	}
+
+	return &HTTPSignatureSigner{keyID: keyID, key: key}, nil
+}
+
+func (signer *HTTPSignatureSigner) Sign(req *http.Request, body []byte) error {
+	if req.Header.Get("Date") == "" {
+		return errors.New("request to sign has no Date header")
+	}
+
+	headers := []string{"(request-target)", "host", "date"}
+	if body != nil {
+		req.Header.Set("Digest", Digest(body))
+		headers = append(headers, "digest", "content-type")
+	}
+
+	hash := sha256.Sum256([]byte(SigningString(req, headers)))
+	signature, err := signer.key.Sign(rand.Reader, hash[:], crypto.SHA256)
+	if err != nil {
+		return fmt.Errorf("signing request: %w", err)
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf(`Signature keyId="%s",algorithm="rsa-sha256",headers="%s",signature="%s"`,
+		signer.keyID, strings.Join(headers, " "), base64.StdEncoding.EncodeToString(signature)))
+	return nil
+}
+
+// Digest is the value of the Digest header for body, its base64 encoded SHA-256 hash
+func Digest(body []byte) string {
+	hash := sha256.Sum256(body)
+	return "SHA-256=" + base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// SigningString is the string that's signed for the request, each of headers
+// as a lower case "name: value" line, with (request-target) standing for
+// the request's method and path. It's exported for verifying signatures
+func SigningString(req *http.Request, headers []string) string {
+	lines := make([]string, 0, len(headers))
+	for _, header := range headers {
+		var value string
+		switch header {
+		case "(request-target)":
+			value = strings.ToLower(req.Method) + " " + req.URL.RequestURI()
+		case "host":
+			value = req.Host
+			if value == "" {
+				value = req.URL.Host
+			}
+		default:
+			value = req.Header.Get(header)
+		}
+		lines = append(lines, header+": "+value)
+	}
+	return strings.Join(lines, "\n")
+}
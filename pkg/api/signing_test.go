@@ -0,0 +1,99 @@
+package api
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var signatureParams = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// verifyingServer checks every request it's sent is signed by key
+func verifyingServer(t *testing.T, key *rsa.PublicKey, verified *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization := r.Header.Get("Authorization")
+		assert.True(t, strings.HasPrefix(authorization, "Signature "))
+		params := map[string]string{}
+		for _, match := range signatureParams.FindAllStringSubmatch(authorization, -1) {
+			params[match[1]] = match[2]
+		}
+		assert.Equal(t, "key-1", params["keyId"])
+		assert.Equal(t, "rsa-sha256", params["algorithm"])
+
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		if len(body) > 0 {
+			assert.Equal(t, Digest(body), r.Header.Get("Digest"))
+		}
+
+		signature, err := base64.StdEncoding.DecodeString(params["signature"])
+		assert.NoError(t, err)
+		hash := sha256.Sum256([]byte(SigningString(r, strings.Split(params["headers"], " "))))
+		if assert.NoError(t, rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], signature)) {
+			*verified = append(*verified, params["headers"])
+		}
+		_, err = w.Write([]byte("{}"))
+		assert.NoError(t, err)
+	}))
+}
+
+func TestApiSignsRequests(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	verified := []string{}
+	server := verifyingServer(t, &key.PublicKey, &verified)
+	defer server.Close()
+
+	options, err := NewOptions(WithBaseURL(server.URL), WithHTTPClient(server.Client()), WithSigningKey("key-1", key))
+	assert.NoError(t, err)
+	api := createBaseApi(options)
+
+	_, err = api.post(context.Background(), "/v1/organisation/accounts", []byte(`{"data": {}}`))
+	assert.NoError(t, err)
+	_, err = api.get(context.Background(), "/v1/organisation/accounts", map[string][]string{"page[size]": {"1"}})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"(request-target) host date digest content-type",
+		"(request-target) host date",
+	}, verified)
+}
+
+func TestSigningStringRequestTarget(t *testing.T) {
+	req, err := http.NewRequest("POST", "https://api.example.com/v1/organisation/accounts?version=1", nil)
+	assert.NoError(t, err)
+	req.Header.Set("Date", "Mon, 14 Jun 2021 19:51:27 GMT")
+
+	assert.Equal(t, "(request-target): post /v1/organisation/accounts?version=1\n"+
+		"host: api.example.com\n"+
+		"date: Mon, 14 Jun 2021 19:51:27 GMT", SigningString(req, []string{"(request-target)", "host", "date"}))
+}
+
+func TestSigningKeyMustBeRSA(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	_, err = NewOptions(WithSigningKey("key-1", key))
+	assert.True(t, errors.Is(err, ErrInvalidOption))
+	_, err = NewOptions(WithSigningKey("", nil))
+	assert.True(t, errors.Is(err, ErrInvalidOption))
+	_, err = NewOptions(WithSigningKey("key-1", (*rsa.PrivateKey)(nil)))
+	assert.EqualError(t, err, "invalid option: signing key must not be nil")
+	_, err = NewOptions(WithRequestSigner(nil))
+	assert.True(t, errors.Is(err, ErrInvalidOption))
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/jonorademaker/finance_api_client/pkg/models"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName is the name of the tracer spans are created with
+const instrumentationName = "github.com/jonorademaker/finance_api_client/pkg/api"
+
+// Span attributes added on top of the OpenTelemetry semantic conventions
+const (
+	// RetryCountKey is the number of attempts at the request before this one
+	RetryCountKey = attribute.Key("financeapi.retry_count")
+	// ErrorClassKey is why the request or operation failed, see ErrorClass
+	ErrorClassKey = attribute.Key("financeapi.error_class")
+)
+
+type operationKey struct{}
+
+// Operation is the name of the Api method ctx is being used by, e.g.
+// "OrganisationalAccounts.Create", empty if it isn't being used by one
+func Operation(ctx context.Context) string {
+	operation, _ := ctx.Value(operationKey{}).(string)
+	return operation
+}
+
+// startOperation marks ctx as being used by the Api method name and, when tracing,
+// starts its span. The function returned ends the operation with its result
+func (api *baseApi) startOperation(ctx context.Context, name string) (context.Context, func(err error)) {
+	ctx = context.WithValue(ctx, operationKey{}, name)
+	if api.tracer == nil {
+		return ctx, func(error) {}
+	}
+
+	ctx, span := api.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindInternal))
+	return ctx, func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			span.SetAttributes(ErrorClassKey.String(ErrorClass(0, err)))
+		}
+		span.End()
+	}
+}
+
+// TracingMiddleware creates a span for each attempt at a request, as a child of the
+// span in its context, and propagates it to the api with a W3C traceparent header
+func TracingMiddleware(provider trace.TracerProvider) Middleware {
+	tracer := provider.Tracer(instrumentationName)
+	propagator := propagation.TraceContext{}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			ctx, span := tracer.Start(req.Context(), "HTTP "+req.Method,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(
+					semconv.HTTPMethodKey.String(req.Method),
+					semconv.HTTPURLKey.String(models.RedactQuery(req.URL).String()),
+					semconv.NetPeerNameKey.String(req.URL.Hostname()),
+				))
+			defer span.End()
+			if attempt := Attempt(ctx); attempt > 0 {
+				span.SetAttributes(RetryCountKey.Int(attempt - 1))
+			}
+
+			traced := req.Clone(ctx)
+			propagator.Inject(ctx, propagation.HeaderCarrier(traced.Header))
+
+			resp, err := next.RoundTrip(traced)
+			statusCode := 0
+			if resp != nil {
+				statusCode = resp.StatusCode
+				span.SetAttributes(semconv.HTTPStatusCodeKey.Int(statusCode))
+			}
+			if err != nil || !isSuccessResponse(resp) {
+				span.SetAttributes(ErrorClassKey.String(ErrorClass(statusCode, err)))
+				if err != nil {
+					span.RecordError(err)
+					span.SetStatus(codes.Error, err.Error())
+				} else {
+					span.SetStatus(codes.Error, http.StatusText(statusCode))
+				}
+			}
+			return resp, err
+		})
+	}
+}
+
+// ErrorClass is a short, low cardinality, description of why a request failed,
+// given its status code, or 0 if it didn't get a response, and error, e.g.
+// "not_found", "server" or "timeout"
+func ErrorClass(statusCode int, err error) string {
+	// a transport error may have the status of the response it failed to read
+	if apiError := (models.FinanceApiError{}); statusCode == 0 && errors.As(err, &apiError) && !errors.Is(err, models.ErrTransport) {
+		statusCode = apiError.StatusCode
+	}
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "cancelled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, ErrCircuitOpen):
+		return "circuit_open"
+	case errors.Is(err, models.ErrVersionConflict):
+		return "version_conflict"
+	case statusCode == http.StatusNotFound:
+		return "not_found"
+	case statusCode == http.StatusConflict:
+		return "conflict"
+	case statusCode == http.StatusTooManyRequests:
+		return "rate_limited"
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return "unauthorised"
+	case statusCode == http.StatusBadRequest || statusCode == http.StatusUnprocessableEntity || errors.Is(err, models.ErrValidation):
+		return "validation"
+	case statusCode >= 500:
+		return "server"
+	case statusCode >= 400:
+		return "client"
+	case errors.Is(err, models.ErrTransport) || errors.As(err, new(*url.Error)):
+		return "transport"
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jonorademaker/finance_api_client/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func spanAttributes(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	attributes := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes {
+		attributes[kv.Key] = kv.Value
+	}
+	return attributes
+}
+
+func TestTracingSpans(t *testing.T) {
+	count := 0
+	traceparents := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count += 1
+		traceparents = append(traceparents, r.Header.Get("traceparent"))
+		if count == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": {"type": "accounts", "id": "48e51a61-29e2-44e6-a97d-4bcf3bda92fc"}}`))
+	}))
+	defer server.Close()
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	api, err := NewApiWithOptions(WithBaseURL(server.URL), WithTracerProvider(provider), WithRetryStrategy(ConstantBackoff(0, 2)))
+	assert.NoError(t, err)
+
+	_, err = api.OrganisationalAccounts.Fetch("48e51a61-29e2-44e6-a97d-4bcf3bda92fc")
+	assert.NoError(t, err)
+
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 3)
+	first, second, operation := spans[0], spans[1], spans[2]
+
+	assert.Equal(t, "OrganisationalAccounts.Fetch", operation.Name)
+	assert.Equal(t, trace.SpanKindInternal, operation.SpanKind)
+	assert.Equal(t, codes.Unset, operation.Status.Code)
+
+	for i, attempt := range []tracetest.SpanStub{first, second} {
+		assert.Equal(t, "HTTP GET", attempt.Name)
+		assert.Equal(t, trace.SpanKindClient, attempt.SpanKind)
+		assert.Equal(t, operation.SpanContext.SpanID(), attempt.Parent.SpanID())
+		assert.Equal(t, operation.SpanContext.TraceID(), attempt.SpanContext.TraceID())
+		assert.Equal(t, int64(i), spanAttributes(attempt)[RetryCountKey].AsInt64())
+		assert.Equal(t, "00-"+attempt.SpanContext.TraceID().String()+"-"+attempt.SpanContext.SpanID().String()+"-01", traceparents[i])
+	}
+
+	assert.Equal(t, int64(503), spanAttributes(first)[semconv.HTTPStatusCodeKey].AsInt64())
+	assert.Equal(t, "server", spanAttributes(first)[ErrorClassKey].AsString())
+	assert.Equal(t, codes.Error, first.Status.Code)
+	assert.Equal(t, int64(200), spanAttributes(second)[semconv.HTTPStatusCodeKey].AsInt64())
+	assert.Equal(t, codes.Unset, second.Status.Code)
+}
+
+func TestTracingFailedOperation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	api, err := NewApiWithOptions(WithBaseURL(server.URL), WithTracerProvider(provider))
+	assert.NoError(t, err)
+
+	parent, span := provider.Tracer("test").Start(context.Background(), "parent")
+	err = api.OrganisationalAccounts.DeleteWithContext(parent, "48e51a61-29e2-44e6-a97d-4bcf3bda92fc", 0)
+	span.End()
+	assert.True(t, errors.Is(err, models.ErrNotFound))
+
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 3)
+	operation := spans[1]
+	assert.Equal(t, "OrganisationalAccounts.Delete", operation.Name)
+	assert.Equal(t, span.SpanContext().SpanID(), operation.Parent.SpanID())
+	assert.Equal(t, codes.Error, operation.Status.Code)
+	assert.Equal(t, "not_found", spanAttributes(operation)[ErrorClassKey].AsString())
+	assert.Len(t, operation.Events, 1)
+}
+
+func TestOperationName(t *testing.T) {
+	operations := []string{}
+	record := func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			operations = append(operations, Operation(req.Context()))
+			return next.RoundTrip(req)
+		})
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data": [], "status": "up"}`))
+	}))
+	defer server.Close()
+
+	api, err := NewApiWithOptions(WithBaseURL(server.URL), WithMiddleware(record))
+	assert.NoError(t, err)
+	_, err = api.OrganisationalAccounts.List(ListOptions{})
+	assert.NoError(t, err)
+	_, err = api.Health()
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"OrganisationalAccounts.List", "Health"}, operations)
+	assert.Equal(t, "", Operation(context.Background()))
+}
+
+func TestErrorClass(t *testing.T) {
+	tests := map[string]struct {
+		statusCode int
+		err        error
+	}{
+		"not_found":        {statusCode: 404},
+		"rate_limited":     {statusCode: 429},
+		"client":           {statusCode: 418},
+		"validation":       {err: models.ValidationErrors{{Field: "id"}}},
+		"conflict":         {err: models.FinanceApiError{StatusCode: 409}},
+		"version_conflict": {err: models.VersionConflictError{Err: models.FinanceApiError{StatusCode: 409}}},
+		"timeout":          {err: models.NewTransportError("", context.DeadlineExceeded)},
+		"transport":        {err: models.NewTransportError("", errors.New("connection refused"))},
+		"other":            {err: errors.New("unexpected")},
+	}
+
+	for class, test := range tests {
+		assert.Equal(t, class, ErrorClass(test.statusCode, test.err))
+	}
+}
@@ -0,0 +1,100 @@
+package iban
+
+// span is the position of a component within the BBAN, from start up to end
+type span struct {
+	start int
+	end   int
+}
+
+// countryFormat is how a country's IBANs are laid out, components
+// left empty aren't defined for the country or aren't known
+type countryFormat struct {
+	length  int
+	bank    span
+	branch  span
+	account span
+}
+
+// formats is the IBAN registry's length for each country, along with where the
+// bank, branch and account number are in the BBAN for the countries we support
+var formats = map[string]countryFormat{
+	"AD": {length: 24, bank: span{0, 4}, branch: span{4, 8}, account: span{8, 20}},
+	"AE": {length: 23, bank: span{0, 3}, account: span{3, 19}},
+	"AL": {length: 28},
+	"AT": {length: 20, bank: span{0, 5}, account: span{5, 16}},
+	"AZ": {length: 28},
+	"BA": {length: 20},
+	"BE": {length: 16, bank: span{0, 3}, account: span{3, 10}},
+	"BG": {length: 22, bank: span{0, 4}, branch: span{4, 8}, account: span{10, 18}},
+	"BH": {length: 22},
+	"BR": {length: 29},
+	"BY": {length: 28},
+	"CH": {length: 21, bank: span{0, 5}, account: span{5, 17}},
+	"CR": {length: 22},
+	"CY": {length: 28, bank: span{0, 3}, branch: span{3, 8}, account: span{8, 24}},
+	"CZ": {length: 24, bank: span{0, 4}, account: span{4, 20}},
+	"DE": {length: 22, bank: span{0, 8}, account: span{8, 18}},
+	"DK": {length: 18, bank: span{0, 4}, account: span{4, 14}},
+	"DO": {length: 28},
+	"EE": {length: 20, bank: span{0, 2}, account: span{2, 16}},
+	"EG": {length: 29},
+	"ES": {length: 24, bank: span{0, 4}, branch: span{4, 8}, account: span{10, 20}},
+	"FI": {length: 18, bank: span{0, 3}, account: span{3, 14}},
+	"FO": {length: 18},
+	"FR": {length: 27, bank: span{0, 5}, branch: span{5, 10}, account: span{10, 21}},
+	"GB": {length: 22, bank: span{0, 4}, branch: span{4, 10}, account: span{10, 18}},
+	"GE": {length: 22},
+	"GI": {length: 23},
+	"GL": {length: 18},
+	"GR": {length: 27, bank: span{0, 3}, branch: span{3, 7}, account: span{7, 23}},
+	"GT": {length: 28},
+	"HR": {length: 21, bank: span{0, 7}, account: span{7, 17}},
+	"HU": {length: 28, bank: span{0, 3}, branch: span{3, 7}, account: span{8, 23}},
+	"IE": {length: 22, bank: span{0, 4}, branch: span{4, 10}, account: span{10, 18}},
+	"IL": {length: 23},
+	"IQ": {length: 23},
+	"IS": {length: 26},
+	"IT": {length: 27, bank: span{1, 6}, branch: span{6, 11}, account: span{11, 23}},
+	"JO": {length: 30},
+	"KW": {length: 30},
+	"KZ": {length: 20},
+	"LB": {length: 28},
+	"LC": {length: 32},
+	"LI": {length: 21, bank: span{0, 5}, account: span{5, 17}},
+	"LT": {length: 20, bank: span{0, 5}, account: span{5, 16}},
+	"LU": {length: 20, bank: span{0, 3}, account: span{3, 16}},
+	"LV": {length: 21, bank: span{0, 4}, account: span{4, 17}},
+	"LY": {length: 25},
+	"MC": {length: 27, bank: span{0, 5}, branch: span{5, 10}, account: span{10, 21}},
+	"MD": {length: 24},
+	"ME": {length: 22},
+	"MK": {length: 19},
+	"MR": {length: 27},
+	"MT": {length: 31, bank: span{0, 4}, branch: span{4, 9}, account: span{9, 27}},
+	"MU": {length: 30},
+	"NL": {length: 18, bank: span{0, 4}, account: span{4, 14}},
+	"NO": {length: 15, bank: span{0, 4}, account: span{4, 10}},
+	"PK": {length: 24},
+	"PL": {length: 28, bank: span{0, 8}, account: span{8, 24}},
+	"PS": {length: 29},
+	"PT": {length: 25, bank: span{0, 4}, branch: span{4, 8}, account: span{8, 19}},
+	"QA": {length: 29},
+	"RO": {length: 24, bank: span{0, 4}, account: span{4, 20}},
+	"RS": {length: 22},
+	"SA": {length: 24},
+	"SC": {length: 31},
+	"SD": {length: 18},
+	"SE": {length: 24, bank: span{0, 3}, account: span{3, 20}},
+	"SI": {length: 19, bank: span{0, 5}, account: span{5, 13}},
+	"SK": {length: 24, bank: span{0, 4}, account: span{4, 20}},
+	"SM": {length: 27, bank: span{1, 6}, branch: span{6, 11}, account: span{11, 23}},
+	"ST": {length: 25},
+	"SV": {length: 28},
+	"TL": {length: 23},
+	"TN": {length: 24},
+	"TR": {length: 26},
+	"UA": {length: 29},
+	"VA": {length: 22},
+	"VG": {length: 24},
+	"XK": {length: 20},
+}
@@ -0,0 +1,108 @@
+package iban
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// bbanBuilder creates a country's BBAN from a bank id and account number
+type bbanBuilder func(bankID string, accountNumber string) (string, error)
+
+// builders are the countries whose BBAN is made up of just the bank id, the
+// account number and check digits that can be calculated from them. Others,
+// such as GB and NL, need a bank code taken from the BIC so can't be derived
+var builders = map[string]bbanBuilder{
+	"BE": func(bankID string, accountNumber string) (string, error) {
+		bban := bankID + accountNumber
+		number, err := strconv.ParseInt(bban, 10, 64)
+		if err != nil || len(bban) != 10 {
+			return "", fmt.Errorf("%w: BE needs a 3 digit bank id and 7 digit account number", ErrNotDerivable)
+		}
+		check := number % 97
+		if check == 0 {
+			check = 97
+		}
+		return fmt.Sprintf("%s%02d", bban, check), nil
+	},
+	"CH": padded(5, 12),
+	"DE": padded(8, 10),
+	"ES": func(bankID string, accountNumber string) (string, error) {
+		if len(bankID) != 8 || !isDigits(bankID) || len(accountNumber) > 10 || !isDigits(accountNumber) {
+			return "", fmt.Errorf("%w: ES needs an 8 digit bank id and up to 10 digit account number", ErrNotDerivable)
+		}
+		accountNumber = leftPad(accountNumber, 10)
+		return bankID + spanishCheckDigit("00"+bankID) + spanishCheckDigit(accountNumber) + accountNumber, nil
+	},
+	"GR": padded(7, 16),
+	"LU": padded(3, 13),
+	"PL": padded(8, 16),
+}
+
+// Generate derives the IBAN, in electronic format, for an account identified by its
+// country, bank id and account number, as in models.OrganisationAccountAttributes.
+// It returns an error wrapping ErrNotDerivable for countries where that isn't possible
+func Generate(country string, bankID string, accountNumber string) (string, error) {
+	country = strings.ToUpper(country)
+	builder, ok := builders[country]
+	if !ok {
+		return "", fmt.Errorf("%w for %s", ErrNotDerivable, country)
+	}
+
+	bban, err := builder(strings.ToUpper(bankID), strings.ToUpper(accountNumber))
+	if err != nil {
+		return "", err
+	}
+
+	value := country + CheckDigits(country, bban) + bban
+	if err := Validate(value); err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// padded builds the BBAN for countries where it's the bank id followed by
+// the account number, with the account number zero padded to its full length
+func padded(bankLength int, accountLength int) bbanBuilder {
+	return func(bankID string, accountNumber string) (string, error) {
+		if len(bankID) != bankLength || len(accountNumber) == 0 || len(accountNumber) > accountLength {
+			return "", fmt.Errorf("%w: needs a %d character bank id and up to %d character account number",
+				ErrNotDerivable, bankLength, accountLength)
+		}
+		return bankID + leftPad(accountNumber, accountLength), nil
+	}
+}
+
+// spanishCheckDigit is the "dígito de control" for 10 digits
+func spanishCheckDigit(value string) string {
+	weights := []int{1, 2, 4, 8, 5, 10, 9, 7, 3, 6}
+	sum := 0
+	for i, char := range value {
+		sum += int(char-'0') * weights[i]
+	}
+
+	digit := 11 - sum%11
+	switch digit {
+	case 11:
+		digit = 0
+	case 10:
+		digit = 1
+	}
+	return strconv.Itoa(digit)
+}
+
+func leftPad(value string, length int) string {
+	if len(value) >= length {
+		return value
+	}
+	return strings.Repeat("0", length-len(value)) + value
+}
+
+func isDigits(value string) bool {
+	for _, char := range value {
+		if char < '0' || char > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,139 @@
+// Package iban validates, formats and generates International Bank Account
+// Numbers, e.g. for models.OrganisationAccountAttributes.Iban
+package iban
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrUnknownCountry    = errors.New("unknown iban country")
+	ErrInvalidLength     = errors.New("invalid iban length")
+	ErrInvalidCharacters = errors.New("invalid iban characters")
+	ErrInvalidChecksum   = errors.New("invalid iban check digits")
+	// ErrNotDerivable is returned by Generate for countries where the IBAN
+	// can't be worked out from just the bank id and account number
+	ErrNotDerivable = errors.New("iban can't be derived")
+)
+
+// IBAN is a parsed IBAN, BankCode, BranchCode and AccountNumber are
+// only filled in for countries whose layout is known
+type IBAN struct {
+	Country       string
+	CheckDigits   string
+	BBAN          string
+	BankCode      string
+	BranchCode    string
+	AccountNumber string
+}
+
+// Parse validates value, in either electronic or print format,
+// and splits it into its components
+func Parse(value string) (IBAN, error) {
+	electronic := Normalize(value)
+	if len(electronic) < 4 {
+		return IBAN{}, fmt.Errorf("%w: %q", ErrInvalidLength, value)
+	}
+
+	for i, char := range electronic {
+		isLetter := char >= 'A' && char <= 'Z'
+		isDigit := char >= '0' && char <= '9'
+		if (i < 2 && !isLetter) || (i >= 2 && i < 4 && !isDigit) || (!isLetter && !isDigit) {
+			return IBAN{}, fmt.Errorf("%w: %q", ErrInvalidCharacters, value)
+		}
+	}
+
+	country := electronic[:2]
+	format, ok := formats[country]
+	if !ok {
+		return IBAN{}, fmt.Errorf("%w: %s", ErrUnknownCountry, country)
+	}
+	if len(electronic) != format.length {
+		return IBAN{}, fmt.Errorf("%w: %s IBANs are %d characters, got %d", ErrInvalidLength, country, format.length, len(electronic))
+	}
+	if mod97(electronic[4:]+electronic[:4]) != 1 {
+		return IBAN{}, fmt.Errorf("%w: %q", ErrInvalidChecksum, value)
+	}
+
+	bban := electronic[4:]
+	return IBAN{
+		Country:       country,
+		CheckDigits:   electronic[2:4],
+		BBAN:          bban,
+		BankCode:      format.bank.of(bban),
+		BranchCode:    format.branch.of(bban),
+		AccountNumber: format.account.of(bban),
+	}, nil
+}
+
+// Validate checks value is an IBAN with the right length for its country and
+// valid check digits, returning an error wrapping one of the Err variables if not
+func Validate(value string) error {
+	_, err := Parse(value)
+	return err
+}
+
+// IsValid is whether value passes Validate, for when the reason it
+// doesn't isn't needed
+func IsValid(value string) bool {
+	return Validate(value) == nil
+}
+
+// Normalize converts an IBAN to electronic format, upper case without any
+// spaces, e.g. "IBAN GB82 WEST 1234 5698 7654 32" to "GB82WEST12345698765432"
+func Normalize(value string) string {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	value = strings.TrimPrefix(value, "IBAN")
+	return strings.Join(strings.Fields(value), "")
+}
+
+// PrintFormat converts an IBAN to print format, upper case in
+// groups of four characters, e.g. "GB82 WEST 1234 5698 7654 32"
+func PrintFormat(value string) string {
+	electronic := Normalize(value)
+
+	groups := []string{}
+	for len(electronic) > 4 {
+		groups = append(groups, electronic[:4])
+		electronic = electronic[4:]
+	}
+	return strings.Join(append(groups, electronic), " ")
+}
+
+func (i IBAN) String() string {
+	return i.Country + i.CheckDigits + i.BBAN
+}
+
+// PrintFormat is the IBAN in groups of four characters
+func (i IBAN) PrintFormat() string {
+	return PrintFormat(i.String())
+}
+
+// CheckDigits calculates the two check digits of the IBAN for the country and BBAN
+func CheckDigits(country string, bban string) string {
+	remainder := mod97(strings.ToUpper(bban + country + "00"))
+	return fmt.Sprintf("%02d", 98-remainder)
+}
+
+// mod97 is the remainder of the number the value represents when divided by 97,
+// with letters standing for the numbers 10 to 35
+func mod97(value string) int {
+	remainder := 0
+	for _, char := range value {
+		if char >= 'A' && char <= 'Z' {
+			remainder = (remainder*100 + int(char-'A') + 10) % 97
+		} else {
+			remainder = (remainder*10 + int(char-'0')) % 97
+		}
+	}
+	return remainder
+}
+
+func (s span) of(bban string) string {
+	if s.end == 0 || s.end > len(bban) {
+		return ""
+	}
+	return bban[s.start:s.end]
+}
@@ -0,0 +1,137 @@
+// +build unit
+
+package iban
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidIbans(t *testing.T) {
+	t.Parallel()
+
+	valid := []string{
+		"GB82WEST12345698765432",
+		"DE89370400440532013000",
+		"FR1420041010050500013M02606",
+		"ES9121000418450200051332",
+		"IT60X0542811101000000123456",
+		"NL91ABNA0417164300",
+		"BE68539007547034",
+		"CH9300762011623852957",
+		"LU280019400644750000",
+		"GR1601101250000000012300695",
+		"PL61109010140000071219812874",
+		"PT50000201231234567890154",
+		"gb82 west 1234 5698 7654 32",
+		"IBAN GB82 WEST 1234 5698 7654 32",
+	}
+	for _, value := range valid {
+		assert.NoError(t, Validate(value), value)
+	}
+}
+
+func TestInvalidIbans(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]error{
+		"GB82WEST12345698765433":  ErrInvalidChecksum,
+		"GB82WEST1234569876543":   ErrInvalidLength,
+		"GB82WEST1234569876543_2": ErrInvalidCharacters,
+		"1B82WEST12345698765432":  ErrInvalidCharacters,
+		"GBX2WEST12345698765432":  ErrInvalidCharacters,
+		"ZZ82WEST12345698765432":  ErrUnknownCountry,
+		"GB8":                     ErrInvalidLength,
+	}
+	for value, expected := range tests {
+		err := Validate(value)
+		assert.True(t, errors.Is(err, expected), "%s: %v", value, err)
+		assert.False(t, IsValid(value))
+	}
+}
+
+func TestParseComponents(t *testing.T) {
+	t.Parallel()
+
+	parsed, err := Parse("GB82 WEST 1234 5698 7654 32")
+	assert.NoError(t, err)
+	assert.Equal(t, IBAN{
+		Country:       "GB",
+		CheckDigits:   "82",
+		BBAN:          "WEST12345698765432",
+		BankCode:      "WEST",
+		BranchCode:    "123456",
+		AccountNumber: "98765432",
+	}, parsed)
+	assert.Equal(t, "GB82WEST12345698765432", parsed.String())
+
+	parsed, err = Parse("IT60X0542811101000000123456")
+	assert.NoError(t, err)
+	assert.Equal(t, "05428", parsed.BankCode)
+	assert.Equal(t, "11101", parsed.BranchCode)
+	assert.Equal(t, "000000123456", parsed.AccountNumber)
+
+	parsed, err = Parse("DE89370400440532013000")
+	assert.NoError(t, err)
+	assert.Equal(t, "37040044", parsed.BankCode)
+	assert.Equal(t, "", parsed.BranchCode)
+	assert.Equal(t, "0532013000", parsed.AccountNumber)
+}
+
+func TestFormats(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "GB82WEST12345698765432", Normalize(" gb82 west 1234 5698 7654 32 "))
+	assert.Equal(t, "GB82 WEST 1234 5698 7654 32", PrintFormat("GB82WEST12345698765432"))
+	assert.Equal(t, "NL91 ABNA 0417 1643 00", PrintFormat("nl91abna0417164300"))
+
+	parsed, err := Parse("BE68539007547034")
+	assert.NoError(t, err)
+	assert.Equal(t, "BE68 5390 0754 7034", parsed.PrintFormat())
+}
+
+func TestCheckDigits(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "82", CheckDigits("GB", "WEST12345698765432"))
+	assert.Equal(t, "14", CheckDigits("FR", "20041010050500013M02606"))
+}
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		country       string
+		bankID        string
+		accountNumber string
+		expected      string
+	}{
+		{"DE", "37040044", "532013000", "DE89370400440532013000"},
+		{"BE", "539", "0075470", "BE68539007547034"},
+		{"ES", "21000418", "0200051332", "ES9121000418450200051332"},
+		{"CH", "00762", "011623852957", "CH9300762011623852957"},
+		{"LU", "001", "9400644750000", "LU280019400644750000"},
+		{"GR", "0110125", "0000000012300695", "GR1601101250000000012300695"},
+		{"PL", "10901014", "0000071219812874", "PL61109010140000071219812874"},
+	}
+	for _, test := range tests {
+		generated, err := Generate(test.country, test.bankID, test.accountNumber)
+		assert.NoError(t, err, test.country)
+		assert.Equal(t, test.expected, generated)
+	}
+}
+
+func TestGenerateNotDerivable(t *testing.T) {
+	t.Parallel()
+
+	_, err := Generate("GB", "400302", "12764204")
+	assert.True(t, errors.Is(err, ErrNotDerivable))
+
+	_, err = Generate("DE", "3704", "532013000")
+	assert.True(t, errors.Is(err, ErrNotDerivable))
+
+	_, err = Generate("ES", "21000418", "02000A1332")
+	assert.True(t, errors.Is(err, ErrNotDerivable))
+}
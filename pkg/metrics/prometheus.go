@@ -0,0 +1,89 @@
+// Package metrics adapts api.Metrics to Prometheus
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// circuitStates are the values of the circuit breaker state gauge
+var circuitStates = map[string]float64{
+	"closed":    0,
+	"half_open": 1,
+	"open":      2,
+}
+
+// Prometheus records the client's metrics as Prometheus collectors:
+//
+//	<namespace>_requests_total{operation, method, status_class}
+//	<namespace>_request_duration_seconds{operation, method}
+//	<namespace>_retries_total{operation, method}
+//	<namespace>_requests_in_flight
+//	<namespace>_circuit_breaker_state{base_url}, 0 closed, 1 half open and 2 open
+type Prometheus struct {
+	requests     *prometheus.CounterVec
+	latency      *prometheus.HistogramVec
+	retries      *prometheus.CounterVec
+	inFlight     prometheus.Gauge
+	circuitState *prometheus.GaugeVec
+}
+
+// NewPrometheus creates the collectors, their names prefixed with namespace,
+// e.g. "financeapi", and registers them with registerer
+func NewPrometheus(registerer prometheus.Registerer, namespace string) (*Prometheus, error) {
+	metrics := &Prometheus{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "requests_total",
+			Help:      "Attempts at requests to the api that have finished, by the class of their status code.",
+		}, []string{"operation", "method", "status_class"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "request_duration_seconds",
+			Help:      "Time taken to get a response from the api for each attempt at a request.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"operation", "method"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "retries_total",
+			Help:      "Attempts at requests to the api that weren't the first.",
+		}, []string{"operation", "method"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "requests_in_flight",
+			Help:      "Attempts at requests to the api waiting for a response.",
+		}),
+		circuitState: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "circuit_breaker_state",
+			Help:      "State of the circuit breaker for each api base url, 0 closed, 1 half open and 2 open.",
+		}, []string{"base_url"}),
+	}
+
+	for _, collector := range []prometheus.Collector{metrics.requests, metrics.latency, metrics.retries, metrics.inFlight, metrics.circuitState} {
+		if err := registerer.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+	return metrics, nil
+}
+
+func (metrics *Prometheus) ObserveRequest(operation string, method string, statusClass string, latency time.Duration) {
+	metrics.requests.WithLabelValues(operation, method, statusClass).Inc()
+	metrics.latency.WithLabelValues(operation, method).Observe(latency.Seconds())
+}
+
+func (metrics *Prometheus) IncRetries(operation string, method string) {
+	metrics.retries.WithLabelValues(operation, method).Inc()
+}
+
+func (metrics *Prometheus) AddInFlight(delta int) {
+	metrics.inFlight.Add(float64(delta))
+}
+
+func (metrics *Prometheus) SetCircuitState(baseUrl string, state string) {
+	if value, ok := circuitStates[state]; ok {
+		metrics.circuitState.WithLabelValues(baseUrl).Set(value)
+	}
+}
@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jonorademaker/finance_api_client/pkg/api"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrometheusRecordsRequests(t *testing.T) {
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count += 1
+		if count == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": []}`))
+	}))
+	defer server.Close()
+
+	registry := prometheus.NewRegistry()
+	metrics, err := NewPrometheus(registry, "financeapi")
+	assert.NoError(t, err)
+
+	client, err := api.NewApiWithOptions(api.WithBaseURL(server.URL), api.WithMetrics(metrics), api.WithRetryStrategy(api.ConstantBackoff(0, 2)))
+	assert.NoError(t, err)
+	_, err = client.OrganisationalAccounts.List(api.ListOptions{})
+	assert.NoError(t, err)
+
+	assert.NoError(t, testutil.CollectAndCompare(metrics.requests, strings.NewReader(`
+# HELP financeapi_requests_total Attempts at requests to the api that have finished, by the class of their status code.
+# TYPE financeapi_requests_total counter
+financeapi_requests_total{method="GET",operation="OrganisationalAccounts.List",status_class="2xx"} 1
+financeapi_requests_total{method="GET",operation="OrganisationalAccounts.List",status_class="5xx"} 1
+`)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.retries.WithLabelValues("OrganisationalAccounts.List", "GET")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.inFlight))
+	assert.Equal(t, 1, testutil.CollectAndCount(metrics.latency))
+}
+
+func TestPrometheusCircuitState(t *testing.T) {
+	metrics, err := NewPrometheus(prometheus.NewRegistry(), "financeapi")
+	assert.NoError(t, err)
+
+	metrics.SetCircuitState("https://api.form3.tech", "open")
+	assert.Equal(t, 2.0, testutil.ToFloat64(metrics.circuitState.WithLabelValues("https://api.form3.tech")))
+	metrics.SetCircuitState("https://api.form3.tech", "half_open")
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.circuitState.WithLabelValues("https://api.form3.tech")))
+	metrics.SetCircuitState("https://api.form3.tech", "closed")
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.circuitState.WithLabelValues("https://api.form3.tech")))
+}
+
+func TestPrometheusRegistersOnce(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	_, err := NewPrometheus(registry, "financeapi")
+	assert.NoError(t, err)
+
+	_, err = NewPrometheus(registry, "financeapi")
+	assert.Error(t, err)
+}
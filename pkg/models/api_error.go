@@ -4,13 +4,30 @@ import (
 	json2 "encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"regexp"
+	"strings"
 )
 
 type apiErrorResponse struct {
 	ErrorMessage string `json:"error_message"`
 }
 
+// sentinel errors to check a FinanceApiError against with errors.Is,
+// rather than matching on the status code or message
+var (
+	ErrNotFound    = errors.New("not found")
+	ErrConflict    = errors.New("conflict")
+	ErrValidation  = errors.New("validation failed")
+	ErrRateLimited = errors.New("rate limited")
+	ErrServer      = errors.New("server error")
+	// ErrTransport is a request that failed before a response was received,
+	// e.g. the connection was refused or the request timed out
+	ErrTransport = errors.New("transport error")
+	// ErrVersionConflict is matched by a VersionConflictError
+	ErrVersionConflict = errors.New("version conflict")
+)
+
 // called this FinanceApiError because we don't know where
 // this error could end up in a user's codebase
 type FinanceApiError struct {
@@ -19,6 +36,29 @@ type FinanceApiError struct {
 	Headers    map[string][]string
 	RawBody    string
 	Err        error
+	// FieldErrors are the entries of the api's validation failure list
+	FieldErrors []FieldError
+
+	transport bool
+}
+
+// FieldError is a single problem with a request, e.g. a missing required field
+type FieldError struct {
+	// Field is the name of the offending field, empty if the problem doesn't name one
+	Field string
+	// In is the part of the request the field is in, e.g. body or query
+	In      string
+	Message string
+}
+
+func (f FieldError) Error() string {
+	return f.Message
+}
+
+// NewTransportError is a FinanceApiError for a request that failed
+// before a response was received
+func NewTransportError(url string, err error) FinanceApiError {
+	return FinanceApiError{Url: url, Err: err, transport: true}
 }
 
 func (r FinanceApiError) Error() string {
@@ -29,6 +69,74 @@ func (r FinanceApiError) Error() string {
 	return fmt.Sprintf("Error - %v", r.Err)
 }
 
+func (r FinanceApiError) Unwrap() error {
+	return r.Err
+}
+
+// Is lets errors.Is match the error against the sentinel errors, e.g.
+// errors.Is(err, models.ErrNotFound). A transport error only matches
+// ErrTransport, even if it has the status of a response it failed to read
+func (r FinanceApiError) Is(target error) bool {
+	if r.transport {
+		return target == ErrTransport
+	}
+
+	switch target {
+	case ErrNotFound:
+		return r.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return r.StatusCode == http.StatusConflict
+	case ErrValidation:
+		return r.StatusCode == http.StatusBadRequest || r.StatusCode == http.StatusUnprocessableEntity || len(r.FieldErrors) > 0
+	case ErrRateLimited:
+		return r.StatusCode == http.StatusTooManyRequests
+	case ErrServer:
+		return r.StatusCode >= 500
+	default:
+		return false
+	}
+}
+
+// AccountConflictError is returned when an account can't be created because
+// its id is already used by an account with different details
+type AccountConflictError struct {
+	ID string
+	// Fields are the json names of the fields that differ, see OrganisationAccount.Diff
+	Fields   []string
+	Existing OrganisationAccount
+	// Err is the conflict error returned by the api
+	Err error
+}
+
+func (r AccountConflictError) Error() string {
+	return fmt.Sprintf("Error (Status 409) - account %s already exists with different %s", r.ID, strings.Join(r.Fields, ", "))
+}
+
+func (r AccountConflictError) Unwrap() error {
+	return r.Err
+}
+
+// VersionConflictError is returned when an account can't be changed because
+// it has been modified since the version the change was based on
+type VersionConflictError struct {
+	ID      string
+	Version int
+	// Err is the conflict error returned by the api
+	Err error
+}
+
+func (r VersionConflictError) Error() string {
+	return fmt.Sprintf("Error (Status 409) - account %s has been modified since version %d", r.ID, r.Version)
+}
+
+func (r VersionConflictError) Unwrap() error {
+	return r.Err
+}
+
+func (r VersionConflictError) Is(target error) bool {
+	return target == ErrVersionConflict
+}
+
 func ParseErrorReponse(url string, statusCode int, headers map[string][]string, body []byte) FinanceApiError {
 	apiError := FinanceApiError{
 		Url:        url,
@@ -47,9 +155,41 @@ func ParseErrorReponse(url string, statusCode int, headers map[string][]string,
 
 	sanitizedMessage := removeExtraValidationLines(apiErrorResponse.ErrorMessage)
 	apiError.Err = errors.New(sanitizedMessage)
+	apiError.FieldErrors = parseValidationFailures(sanitizedMessage)
 	return apiError
 }
 
+const validationFailureList = "validation failure list:\n"
+
+var fieldErrorPattern = regexp.MustCompile(`^(\S+) in (body|query|path|header|formData) `)
+
+// parseValidationFailures splits up a message like
+//
+//	validation failure list:
+//	id in body is required
+//	status in body should be one of [pending confirmed failed]
+func parseValidationFailures(errorMessage string) []FieldError {
+	if !strings.HasPrefix(errorMessage, validationFailureList) {
+		return nil
+	}
+
+	fieldErrors := []FieldError{}
+	for _, line := range strings.Split(strings.TrimPrefix(errorMessage, validationFailureList), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		fieldError := FieldError{Message: line}
+		if match := fieldErrorPattern.FindStringSubmatch(line); match != nil {
+			fieldError.Field = match[1]
+			fieldError.In = match[2]
+		}
+		fieldErrors = append(fieldErrors, fieldError)
+	}
+	return fieldErrors
+}
+
 func removeExtraValidationLines(errorMessage string) string {
 	var re = regexp.MustCompile(`(?m)(validation failure list:\n)+`)
 	return re.ReplaceAllString(errorMessage, "validation failure list:\n")
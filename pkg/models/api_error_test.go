@@ -4,6 +4,7 @@ package models
 
 import (
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -31,6 +32,10 @@ func TestApiErrorDeserializesCorrectly(t *testing.T) {
 	assert.Equal(t, 400, apiError.StatusCode)
 	assert.Equal(t, errorMessage, apiError.RawBody)
 	assert.Equal(t, headers, headers)
+	assert.Equal(t, []FieldError{
+		{Field: "status", In: "body", Message: "status in body should be one of [pending confirmed failed]"},
+	}, apiError.FieldErrors)
+	assert.True(t, errors.Is(apiError, ErrValidation))
 }
 
 func TestApiErrorDeserializationHandlesMarhsalError(t *testing.T) {
@@ -55,3 +60,94 @@ func TestApiErrorHandleNoStatusCode(t *testing.T) {
 	assert.EqualError(t, err, "Error - couldn't access localhost")
 
 }
+
+func TestAccountConflictError(t *testing.T) {
+	t.Parallel()
+
+	apiError := FinanceApiError{StatusCode: 409, Err: errors.New("Account cannot be created as it violates a duplicate constraint")}
+	err := AccountConflictError{
+		ID:     "48e51a61-29e2-44e6-a97d-4bcf3bda92fc",
+		Fields: []string{"attributes.name", "attributes.country"},
+		Err:    apiError,
+	}
+
+	assert.EqualError(t, err, "Error (Status 409) - account 48e51a61-29e2-44e6-a97d-4bcf3bda92fc already exists with different attributes.name, attributes.country")
+	assert.Equal(t, apiError, errors.Unwrap(err))
+}
+
+func TestApiErrorParsesValidationFailureList(t *testing.T) {
+	t.Parallel()
+
+	errorMessage := "{\"error_message\":\"validation failure list:\\nvalidation failure list:\\nid in body is required\\norganisation_id in body is required\\nname should have at least 1 items\"}"
+	body, headers := setupTestVariables(errorMessage)
+
+	apiError := ParseErrorReponse("/url/test", 400, headers, body)
+
+	assert.Equal(t, []FieldError{
+		{Field: "id", In: "body", Message: "id in body is required"},
+		{Field: "organisation_id", In: "body", Message: "organisation_id in body is required"},
+		{Message: "name should have at least 1 items"},
+	}, apiError.FieldErrors)
+}
+
+func TestApiErrorMatchesSentinelErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := map[int]error{
+		400: ErrValidation,
+		404: ErrNotFound,
+		409: ErrConflict,
+		422: ErrValidation,
+		429: ErrRateLimited,
+		500: ErrServer,
+		503: ErrServer,
+	}
+	sentinels := []error{ErrNotFound, ErrConflict, ErrValidation, ErrRateLimited, ErrServer, ErrTransport}
+
+	for statusCode, expected := range tests {
+		apiError := FinanceApiError{StatusCode: statusCode, Err: errors.New("failed")}
+		for _, sentinel := range sentinels {
+			assert.Equal(t, sentinel == expected, errors.Is(apiError, sentinel), "%d is %v", statusCode, sentinel)
+		}
+	}
+}
+
+func TestTransportErrorWrapsCause(t *testing.T) {
+	t.Parallel()
+
+	cause := errors.New("connection refused")
+	err := fmt.Errorf("fetching account: %w", NewTransportError("/url/test", cause))
+
+	assert.True(t, errors.Is(err, ErrTransport))
+	assert.True(t, errors.Is(err, cause))
+	assert.False(t, errors.Is(err, ErrServer))
+	assert.False(t, errors.Is(FinanceApiError{Err: cause}, ErrTransport))
+
+	readFailure := NewTransportError("/url/test", cause)
+	readFailure.StatusCode = 503
+	assert.True(t, errors.Is(readFailure, ErrTransport))
+	assert.False(t, errors.Is(readFailure, ErrServer))
+
+	var apiError FinanceApiError
+	assert.True(t, errors.As(err, &apiError))
+	assert.Equal(t, "/url/test", apiError.Url)
+}
+
+func TestAccountConflictErrorIsConflict(t *testing.T) {
+	t.Parallel()
+
+	err := AccountConflictError{Err: FinanceApiError{StatusCode: 409, Err: errors.New("duplicate")}}
+	assert.True(t, errors.Is(err, ErrConflict))
+}
+
+func TestVersionConflictError(t *testing.T) {
+	t.Parallel()
+
+	apiError := FinanceApiError{StatusCode: 409, Err: errors.New("invalid version")}
+	err := VersionConflictError{ID: "48e51a61-29e2-44e6-a97d-4bcf3bda92fc", Version: 3, Err: apiError}
+
+	assert.EqualError(t, err, "Error (Status 409) - account 48e51a61-29e2-44e6-a97d-4bcf3bda92fc has been modified since version 3")
+	assert.True(t, errors.Is(err, ErrVersionConflict))
+	assert.True(t, errors.Is(err, ErrConflict))
+	assert.False(t, errors.Is(apiError, ErrVersionConflict))
+}
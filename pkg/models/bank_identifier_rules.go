@@ -0,0 +1,237 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// Requirement is whether a bank identifier field has to be given for a country
+type Requirement int
+
+const (
+	Optional Requirement = iota
+	Required
+	Forbidden
+)
+
+func (r Requirement) String() string {
+	switch r {
+	case Required:
+		return "required"
+	case Forbidden:
+		return "forbidden"
+	default:
+		return "optional"
+	}
+}
+
+// FieldRule is how a single bank identifier field is validated for a country
+type FieldRule struct {
+	Requirement Requirement
+	// Pattern is what the value has to match when it's given, nil accepts any value
+	Pattern *regexp.Regexp
+	// Format describes Pattern for error messages, e.g. "6 digits"
+	Format string
+}
+
+// CountryRules are the rules for the bank identifier fields
+// of accounts held in a single country
+type CountryRules struct {
+	BankID        FieldRule
+	BankIDCode    FieldRule
+	Bic           FieldRule
+	AccountNumber FieldRule
+	Iban          FieldRule
+}
+
+// Requirements lists whether each field is required, optional or forbidden,
+// keyed by the field's json name
+func (rules CountryRules) Requirements() map[string]Requirement {
+	return map[string]Requirement{
+		"bank_id":        rules.BankID.Requirement,
+		"bank_id_code":   rules.BankIDCode.Requirement,
+		"bic":            rules.Bic.Requirement,
+		"account_number": rules.AccountNumber.Requirement,
+		"iban":           rules.Iban.Requirement,
+	}
+}
+
+var (
+	countryRulesLock sync.RWMutex
+	countryRules     = map[string]CountryRules{}
+)
+
+// RegisterCountryRules sets the rules used for accounts in country,
+// replacing any rules already registered for it
+func RegisterCountryRules(country string, rules CountryRules) {
+	countryRulesLock.Lock()
+	defer countryRulesLock.Unlock()
+	countryRules[country] = rules
+}
+
+// CountryRulesFor returns the rules registered for country,
+// false if there aren't any
+func CountryRulesFor(country string) (CountryRules, bool) {
+	countryRulesLock.RLock()
+	defer countryRulesLock.RUnlock()
+	rules, ok := countryRules[country]
+	return rules, ok
+}
+
+// RegisteredCountries lists the countries that have rules registered, sorted
+func RegisteredCountries() []string {
+	countryRulesLock.RLock()
+	defer countryRulesLock.RUnlock()
+
+	countries := make([]string, 0, len(countryRules))
+	for country := range countryRules {
+		countries = append(countries, country)
+	}
+	sort.Strings(countries)
+	return countries
+}
+
+// ValidateBankIdentifiers checks the bank identifier fields against the rules
+// registered for the account's country, returning ValidationErrors listing every
+// problem found. Countries without registered rules aren't checked
+func (attributes OrganisationAccountAttributes) ValidateBankIdentifiers() error {
+	rules, ok := CountryRulesFor(attributes.Country)
+	if !ok {
+		return nil
+	}
+
+	problems := ValidationErrors{}
+	fields := []struct {
+		name  string
+		value string
+		rule  FieldRule
+	}{
+		{"attributes.bank_id", attributes.BankID, rules.BankID},
+		{"attributes.bank_id_code", attributes.BankIDCode, rules.BankIDCode},
+		// checked as it will be sent, see Serialize
+		{"attributes.bic", NormalizeBIC(attributes.Bic), rules.Bic},
+		{"attributes.account_number", attributes.AccountNumber, rules.AccountNumber},
+		{"attributes.iban", attributes.Iban, rules.Iban},
+	}
+	for _, field := range fields {
+		field.rule.validate(&problems, field.name, field.value, attributes.Country)
+	}
+
+	if len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
+
+func (rule FieldRule) validate(problems *ValidationErrors, field string, value string, country string) {
+	switch {
+	case value == "" && rule.Requirement == Required:
+		problems.add(field, "is required for %s", country)
+	case value != "" && rule.Requirement == Forbidden:
+		problems.add(field, "is not allowed for %s", country)
+	case value != "" && rule.Pattern != nil && !rule.Pattern.MatchString(value):
+		problems.add(field, "should be %s for %s", rule.Format, country)
+	}
+}
+
+var (
+	bicRule  = FieldRule{Pattern: regexp.MustCompile(`^[A-Z]{6}[A-Z0-9]{2}([A-Z0-9]{3})?$`), Format: "an 8 or 11 character BIC"}
+	ibanRule = FieldRule{Pattern: regexp.MustCompile(`^[A-Z]{2}[0-9]{2}[A-Z0-9]{1,30}$`), Format: "an IBAN"}
+)
+
+func digits(count int) FieldRule {
+	return FieldRule{Pattern: regexp.MustCompile(fmt.Sprintf(`^[0-9]{%d}$`, count)), Format: fmt.Sprintf("%d digits", count)}
+}
+
+func digitsBetween(min int, max int) FieldRule {
+	return FieldRule{Pattern: regexp.MustCompile(fmt.Sprintf(`^[0-9]{%d,%d}$`, min, max)), Format: fmt.Sprintf("%d to %d digits", min, max)}
+}
+
+func characters(count int) FieldRule {
+	return FieldRule{Pattern: regexp.MustCompile(fmt.Sprintf(`^[0-9A-Z]{%d}$`, count)), Format: fmt.Sprintf("%d characters", count)}
+}
+
+func pattern(expression string, format string) FieldRule {
+	return FieldRule{Pattern: regexp.MustCompile(expression), Format: format}
+}
+
+func bankIDCode(code string) FieldRule {
+	return FieldRule{Requirement: Required, Pattern: regexp.MustCompile("^" + code + "$"), Format: code}
+}
+
+func (rule FieldRule) required() FieldRule {
+	rule.Requirement = Required
+	return rule
+}
+
+var forbidden = FieldRule{Requirement: Forbidden}
+
+// the rules from https://api-docs.form3.tech/api.html#organisation-accounts
+func init() {
+	RegisterCountryRules("GB", CountryRules{
+		BankID: digits(6).required(), BankIDCode: bankIDCode("GBDSC"), Bic: bicRule.required(),
+		AccountNumber: digits(8), Iban: ibanRule,
+	})
+	RegisterCountryRules("AU", CountryRules{
+		BankID: digits(6), BankIDCode: bankIDCode("AUBSB"), Bic: bicRule.required(),
+		AccountNumber: pattern(`^[1-9][0-9]{5,9}$`, "6 to 10 digits not starting with 0"), Iban: forbidden,
+	})
+	RegisterCountryRules("BE", CountryRules{
+		BankID: digits(3).required(), BankIDCode: bankIDCode("BE"), Bic: bicRule,
+		AccountNumber: digits(7), Iban: ibanRule,
+	})
+	RegisterCountryRules("CA", CountryRules{
+		BankID: pattern(`^0[0-9]{8}$`, "9 digits starting with 0"), BankIDCode: bankIDCode("CACPA"), Bic: bicRule.required(),
+		AccountNumber: digitsBetween(7, 12), Iban: forbidden,
+	})
+	RegisterCountryRules("CH", CountryRules{
+		BankID: digits(5).required(), BankIDCode: bankIDCode("CHBCC"), Bic: bicRule,
+		AccountNumber: characters(12), Iban: ibanRule,
+	})
+	RegisterCountryRules("DE", CountryRules{
+		BankID: digits(8).required(), BankIDCode: bankIDCode("DEBLZ"), Bic: bicRule,
+		AccountNumber: digits(7), Iban: ibanRule,
+	})
+	RegisterCountryRules("ES", CountryRules{
+		BankID: digits(8).required(), BankIDCode: bankIDCode("ESNCC"), Bic: bicRule,
+		AccountNumber: digits(10), Iban: ibanRule,
+	})
+	RegisterCountryRules("FR", CountryRules{
+		BankID: characters(10).required(), BankIDCode: bankIDCode("FR"), Bic: bicRule,
+		AccountNumber: characters(10), Iban: ibanRule,
+	})
+	RegisterCountryRules("GR", CountryRules{
+		BankID: digits(7).required(), BankIDCode: bankIDCode("GRBIC"), Bic: bicRule,
+		AccountNumber: digits(16), Iban: ibanRule,
+	})
+	RegisterCountryRules("HK", CountryRules{
+		BankID: digits(3), BankIDCode: bankIDCode("HKNCC"), Bic: bicRule.required(),
+		AccountNumber: digitsBetween(9, 12), Iban: forbidden,
+	})
+	RegisterCountryRules("IT", CountryRules{
+		BankID: digitsBetween(10, 11).required(), BankIDCode: bankIDCode("ITNCC"), Bic: bicRule,
+		AccountNumber: digits(12), Iban: ibanRule,
+	})
+	RegisterCountryRules("LU", CountryRules{
+		BankID: digits(3).required(), BankIDCode: bankIDCode("LULUX"), Bic: bicRule,
+		AccountNumber: characters(13), Iban: ibanRule,
+	})
+	RegisterCountryRules("NL", CountryRules{
+		BankID: forbidden, BankIDCode: forbidden, Bic: bicRule.required(),
+		AccountNumber: digits(10), Iban: ibanRule,
+	})
+	RegisterCountryRules("PL", CountryRules{
+		BankID: digits(8).required(), BankIDCode: bankIDCode("PLKNR"), Bic: bicRule,
+		AccountNumber: digits(16), Iban: ibanRule,
+	})
+	RegisterCountryRules("PT", CountryRules{
+		BankID: digits(8).required(), BankIDCode: bankIDCode("PTNCC"), Bic: bicRule,
+		AccountNumber: digits(11), Iban: ibanRule,
+	})
+	RegisterCountryRules("US", CountryRules{
+		BankID: digits(9).required(), BankIDCode: bankIDCode("USABA"), Bic: bicRule.required(),
+		AccountNumber: digitsBetween(6, 17), Iban: forbidden,
+	})
+}
@@ -0,0 +1,111 @@
+// +build unit
+
+package models
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBankIdentifiersValidForGB(t *testing.T) {
+	t.Parallel()
+
+	attributes := OrganisationAccountAttributes{
+		Country:       "GB",
+		BankID:        "400302",
+		BankIDCode:    "GBDSC",
+		Bic:           "NWBKGB42",
+		AccountNumber: "10000004",
+		Iban:          "GB28NWBK40030212764204",
+	}
+	assert.NoError(t, attributes.ValidateBankIdentifiers())
+}
+
+func TestBankIdentifiersNormaliseBic(t *testing.T) {
+	t.Parallel()
+
+	for _, bic := range []string{"nwbkgb42", "NWBK GB42", " nwbk gb 42 xxx "} {
+		attributes := OrganisationAccountAttributes{Country: "GB", BankID: "400302", BankIDCode: "GBDSC", Bic: bic}
+		assert.NoError(t, attributes.ValidateBankIdentifiers(), bic)
+	}
+}
+
+func TestBankIdentifiersInvalidForGB(t *testing.T) {
+	t.Parallel()
+
+	attributes := OrganisationAccountAttributes{
+		Country:       "GB",
+		BankID:        "40030",
+		BankIDCode:    "DEBLZ",
+		AccountNumber: "1000",
+	}
+
+	assert.EqualError(t, attributes.ValidateBankIdentifiers(), "Error - validation failure list:\n"+
+		"attributes.bank_id in body should be 6 digits for GB\n"+
+		"attributes.bank_id_code in body should be GBDSC for GB\n"+
+		"attributes.bic in body is required for GB\n"+
+		"attributes.account_number in body should be 8 digits for GB")
+}
+
+func TestBankIdentifiersForbiddenFields(t *testing.T) {
+	t.Parallel()
+
+	attributes := OrganisationAccountAttributes{
+		Country:    "NL",
+		BankID:     "12345",
+		BankIDCode: "NLBNK",
+		Bic:        "ABNANL2A",
+	}
+
+	err := attributes.ValidateBankIdentifiers()
+	assert.True(t, errors.Is(err, ErrValidation))
+	assert.EqualError(t, err, "Error - validation failure list:\n"+
+		"attributes.bank_id in body is not allowed for NL\n"+
+		"attributes.bank_id_code in body is not allowed for NL")
+}
+
+func TestBankIdentifiersUnknownCountry(t *testing.T) {
+	t.Parallel()
+
+	attributes := OrganisationAccountAttributes{Country: "ZZ", BankID: "anything"}
+	assert.NoError(t, attributes.ValidateBankIdentifiers())
+}
+
+func TestCountryRulesRequirements(t *testing.T) {
+	t.Parallel()
+
+	rules, ok := CountryRulesFor("DE")
+	assert.True(t, ok)
+	assert.Equal(t, map[string]Requirement{
+		"bank_id":        Required,
+		"bank_id_code":   Required,
+		"bic":            Optional,
+		"account_number": Optional,
+		"iban":           Optional,
+	}, rules.Requirements())
+	assert.Equal(t, "required", Required.String())
+}
+
+// not parallel, as the registry is shared with the other tests
+func TestRegisterCountryRules(t *testing.T) {
+	RegisterCountryRules("ZX", CountryRules{
+		BankID: FieldRule{Requirement: Required, Pattern: regexp.MustCompile(`^[0-9]{4}$`), Format: "4 digits"},
+		Iban:   FieldRule{Requirement: Forbidden},
+	})
+	t.Cleanup(func() {
+		countryRulesLock.Lock()
+		defer countryRulesLock.Unlock()
+		delete(countryRules, "ZX")
+	})
+	assert.Contains(t, RegisteredCountries(), "ZX")
+
+	attributes := OrganisationAccountAttributes{Country: "ZX", BankID: "1234"}
+	assert.NoError(t, attributes.ValidateBankIdentifiers())
+
+	attributes.Iban = "ZX00123"
+	assert.EqualError(t, attributes.ValidateBankIdentifiers(), "Error - validation failure list:\n"+
+		"attributes.iban in body is not allowed for ZX")
+}
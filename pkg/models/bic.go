@@ -0,0 +1,72 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalidBIC is wrapped by the errors ParseBIC returns
+var ErrInvalidBIC = errors.New("invalid bic")
+
+var bicPattern = regexp.MustCompile(`^([A-Z]{4})([A-Z]{2})([A-Z0-9]{2})([A-Z0-9]{3})?$`)
+
+// BIC is a SWIFT Business Identifier Code split into its parts, e.g.
+// NWBKGB2LXXX is institution NWBK, country GB, location 2L and branch XXX
+type BIC struct {
+	Institution string
+	Country     string
+	Location    string
+	// Branch is empty for 8 character BICs
+	Branch string
+}
+
+// NormalizeBIC upper cases the value and removes any spaces, the api
+// rejects BICs that are lower case or padded
+func NormalizeBIC(value string) string {
+	return strings.Join(strings.Fields(strings.ToUpper(value)), "")
+}
+
+// ParseBIC normalises and validates an 8 or 11 character BIC
+func ParseBIC(value string) (BIC, error) {
+	match := bicPattern.FindStringSubmatch(NormalizeBIC(value))
+	if match == nil {
+		return BIC{}, fmt.Errorf("%w: %q should be 8 or 11 letters and digits", ErrInvalidBIC, value)
+	}
+	if !iso3166Countries[match[2]] {
+		return BIC{}, fmt.Errorf("%w: %q has unknown country %s", ErrInvalidBIC, value, match[2])
+	}
+
+	return BIC{Institution: match[1], Country: match[2], Location: match[3], Branch: match[4]}, nil
+}
+
+func (b BIC) String() string {
+	return b.Institution + b.Country + b.Location + b.Branch
+}
+
+// IsPrimaryOffice is true for BICs without a branch or with the XXX branch
+func (b BIC) IsPrimaryOffice() bool {
+	return b.Branch == "" || b.Branch == "XXX"
+}
+
+// ValidateBic checks the BIC, when given, is well formed and is for
+// the same country as the account, returning ValidationErrors if not
+func (attributes OrganisationAccountAttributes) ValidateBic() error {
+	if attributes.Bic == "" {
+		return nil
+	}
+
+	problems := ValidationErrors{}
+	bic, err := ParseBIC(attributes.Bic)
+	if err != nil {
+		problems.add("attributes.bic", "should be an 8 or 11 character BIC")
+	} else if attributes.Country != "" && bic.Country != attributes.Country {
+		problems.add("attributes.bic", "should be for country %s, not %s", attributes.Country, bic.Country)
+	}
+
+	if len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+// +build unit
+
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseBIC(t *testing.T) {
+	t.Parallel()
+
+	bic, err := ParseBIC("NWBKGB2LXXX")
+	assert.NoError(t, err)
+	assert.Equal(t, BIC{Institution: "NWBK", Country: "GB", Location: "2L", Branch: "XXX"}, bic)
+	assert.Equal(t, "NWBKGB2LXXX", bic.String())
+	assert.True(t, bic.IsPrimaryOffice())
+
+	bic, err = ParseBIC(" deut de ff 500 ")
+	assert.NoError(t, err)
+	assert.Equal(t, BIC{Institution: "DEUT", Country: "DE", Location: "FF", Branch: "500"}, bic)
+	assert.False(t, bic.IsPrimaryOffice())
+
+	bic, err = ParseBIC("nwbkgb42")
+	assert.NoError(t, err)
+	assert.Equal(t, "NWBKGB42", bic.String())
+	assert.True(t, bic.IsPrimaryOffice())
+}
+
+func TestParseInvalidBIC(t *testing.T) {
+	t.Parallel()
+
+	invalid := []string{"", "NWBKGB4", "NWBKGB42X", "NWBKGB42XXXX", "1WBKGB42", "NWBK1B42", "NWBKZZ42", "NWBK-GB42"}
+	for _, value := range invalid {
+		_, err := ParseBIC(value)
+		assert.True(t, errors.Is(err, ErrInvalidBIC), value)
+	}
+}
+
+func TestValidateBic(t *testing.T) {
+	t.Parallel()
+
+	attributes := OrganisationAccountAttributes{Country: "GB", Bic: "nwbkgb42"}
+	assert.NoError(t, attributes.ValidateBic())
+
+	attributes.Bic = ""
+	assert.NoError(t, attributes.ValidateBic())
+
+	attributes.Bic = "DEUTDEFF"
+	assert.EqualError(t, attributes.ValidateBic(), "Error - validation failure list:\nattributes.bic in body should be for country GB, not DE")
+
+	attributes.Bic = "NWBK"
+	assert.EqualError(t, attributes.ValidateBic(), "Error - validation failure list:\nattributes.bic in body should be an 8 or 11 character BIC")
+}
+
+func TestSerializeNormalizesBic(t *testing.T) {
+	t.Parallel()
+
+	account := OrganisationAccount{Attributes: OrganisationAccountAttributes{Country: "GB", Bic: " nwbk gb42 "}}
+
+	payload, err := account.Serialize()
+	assert.NoError(t, err)
+	assert.Contains(t, string(payload), `"bic": "NWBKGB42"`)
+	assert.Equal(t, " nwbk gb42 ", account.Attributes.Bic)
+}
@@ -0,0 +1,278 @@
+package models
+
+import (
+	json2 "encoding/json"
+	"errors"
+	"sync"
+)
+
+// Resource is a JSON:API resource, anything that can be the data
+// of a Document, e.g. an OrganisationAccount
+type Resource interface {
+	// ResourceType is the resource's JSON:API type, e.g. "accounts"
+	ResourceType() string
+}
+
+// Document is a JSON:API document, the envelope every request
+// and response body of the api is wrapped in
+type Document struct {
+	// Data holds the primary data, a single resource unless Many is true
+	Data []Resource
+	Many bool
+	// Links, Meta, Included and Errors are left empty when the document doesn't have them
+	Links    *Links
+	Meta     map[string]json2.RawMessage
+	Included []Resource
+	Errors   []ErrorObject
+}
+
+// Links are the JSON:API pagination links returned with a list of resources,
+// each one is a url relative to the api's base url and is empty when not relevant
+type Links struct {
+	Self  string `json:"self,omitempty"`
+	First string `json:"first,omitempty"`
+	Prev  string `json:"prev,omitempty"`
+	Next  string `json:"next,omitempty"`
+	Last  string `json:"last,omitempty"`
+}
+
+// ErrorObject is a single entry in a JSON:API document's errors
+type ErrorObject struct {
+	ID     string                      `json:"id,omitempty"`
+	Status string                      `json:"status,omitempty"`
+	Code   string                      `json:"code,omitempty"`
+	Title  string                      `json:"title,omitempty"`
+	Detail string                      `json:"detail,omitempty"`
+	Source *ErrorSource                `json:"source,omitempty"`
+	Meta   map[string]json2.RawMessage `json:"meta,omitempty"`
+}
+
+// ErrorSource points at the part of the request an ErrorObject is about
+type ErrorSource struct {
+	Pointer   string `json:"pointer,omitempty"`
+	Parameter string `json:"parameter,omitempty"`
+}
+
+// RawResource is a resource whose type hasn't been registered, it's
+// kept as the json it was sent as
+type RawResource struct {
+	Type string
+	ID   string
+	Raw  json2.RawMessage
+}
+
+func (r RawResource) ResourceType() string {
+	return r.Type
+}
+
+func (r RawResource) MarshalJSON() ([]byte, error) {
+	return r.Raw, nil
+}
+
+var (
+	resourceTypesLock sync.RWMutex
+	resourceTypes     = map[string]func() Resource{}
+)
+
+// RegisterResourceType tells DeserializeDocument how to decode resources of
+// resourceType. newResource should return a pointer for the json to be decoded into
+func RegisterResourceType(resourceType string, newResource func() Resource) {
+	resourceTypesLock.Lock()
+	defer resourceTypesLock.Unlock()
+	resourceTypes[resourceType] = newResource
+}
+
+// NewDocument is a document with a single resource as its primary data
+func NewDocument(resource Resource) Document {
+	return Document{Data: []Resource{resource}}
+}
+
+// NewCollectionDocument is a document with a list of resources as its primary data
+func NewCollectionDocument(resources []Resource) Document {
+	return Document{Data: resources, Many: true}
+}
+
+// Resource is the document's single primary resource, false if it
+// holds a list or no data
+func (d Document) Resource() (Resource, bool) {
+	if d.Many || len(d.Data) != 1 {
+		return nil, false
+	}
+	return d.Data[0], true
+}
+
+// IncludedResource finds a resource in the document's included resources
+func (d Document) IncludedResource(resourceType string, id string) (Resource, bool) {
+	for _, resource := range d.Included {
+		if resource.ResourceType() == resourceType && resourceID(resource) == id {
+			return resource, true
+		}
+	}
+	return nil, false
+}
+
+// Serialize encodes the document as the indented json sent to the api
+func (d Document) Serialize() ([]byte, error) {
+	data, err := json2.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return nil, FinanceApiError{Err: err}
+	}
+	return data, nil
+}
+
+// DeserializeDocument decodes a document, using the registered resource types
+// to decode its resources and RawResource for any that aren't registered
+func DeserializeDocument(body []byte) (Document, error) {
+	return deserializeDocument(body, nil)
+}
+
+// DeserializeDocumentOf decodes a document whose primary data is known to be of
+// the type newResource creates, e.g. the response of an endpoint for that type.
+// Included resources are decoded as they would be by DeserializeDocument
+func DeserializeDocumentOf(body []byte, newResource func() Resource) (Document, error) {
+	return deserializeDocument(body, newResource)
+}
+
+func deserializeDocument(body []byte, newResource func() Resource) (Document, error) {
+	var document Document
+	err := unmarshalDocument(body, &document, newResource)
+	if err != nil {
+		return Document{}, FinanceApiError{Err: err}
+	}
+	return document, nil
+}
+
+type documentJson struct {
+	Data     json2.RawMessage            `json:"data,omitempty"`
+	Links    *Links                      `json:"links,omitempty"`
+	Meta     map[string]json2.RawMessage `json:"meta,omitempty"`
+	Included []json2.RawMessage          `json:"included,omitempty"`
+	Errors   []ErrorObject               `json:"errors,omitempty"`
+}
+
+func (d Document) MarshalJSON() ([]byte, error) {
+	encoded := documentJson{Links: d.Links, Meta: d.Meta, Errors: d.Errors}
+
+	// a document with errors doesn't need data, otherwise it's always
+	// sent, null standing for no resource when there's only one
+	if len(d.Errors) == 0 || len(d.Data) > 0 {
+		var data interface{}
+		if d.Many {
+			resources := d.Data
+			if resources == nil {
+				resources = []Resource{}
+			}
+			data = resources
+		} else if len(d.Data) == 1 {
+			data = d.Data[0]
+		} else if len(d.Data) > 1 {
+			return nil, errors.New("document with a single resource has more than one")
+		}
+
+		var err error
+		if encoded.Data, err = json2.Marshal(data); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, resource := range d.Included {
+		data, err := json2.Marshal(resource)
+		if err != nil {
+			return nil, err
+		}
+		encoded.Included = append(encoded.Included, data)
+	}
+
+	return json2.Marshal(encoded)
+}
+
+func (d *Document) UnmarshalJSON(body []byte) error {
+	return unmarshalDocument(body, d, nil)
+}
+
+func unmarshalDocument(body []byte, document *Document, newResource func() Resource) error {
+	var decoded documentJson
+	if err := json2.Unmarshal(body, &decoded); err != nil {
+		return err
+	}
+	*document = Document{Links: decoded.Links, Meta: decoded.Meta, Errors: decoded.Errors}
+
+	switch data := decoded.Data; {
+	case len(data) == 0 || string(data) == "null":
+	case data[0] == '[':
+		var items []json2.RawMessage
+		if err := json2.Unmarshal(data, &items); err != nil {
+			return err
+		}
+		document.Many = true
+		document.Data = []Resource{}
+		for _, item := range items {
+			resource, err := decodeResource(item, newResource)
+			if err != nil {
+				return err
+			}
+			document.Data = append(document.Data, resource)
+		}
+	default:
+		resource, err := decodeResource(data, newResource)
+		if err != nil {
+			return err
+		}
+		document.Data = []Resource{resource}
+	}
+
+	for _, item := range decoded.Included {
+		resource, err := decodeResource(item, nil)
+		if err != nil {
+			return err
+		}
+		document.Included = append(document.Included, resource)
+	}
+	return nil
+}
+
+type resourceIdentity struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// decodeResource decodes the json with newResource when it's given,
+// otherwise by looking up the resource's type in the registry
+func decodeResource(data json2.RawMessage, newResource func() Resource) (Resource, error) {
+	if newResource == nil {
+		var identity resourceIdentity
+		if err := json2.Unmarshal(data, &identity); err != nil {
+			return nil, err
+		}
+
+		resourceTypesLock.RLock()
+		newResource = resourceTypes[identity.Type]
+		resourceTypesLock.RUnlock()
+		if newResource == nil {
+			return RawResource{Type: identity.Type, ID: identity.ID, Raw: data}, nil
+		}
+	}
+
+	resource := newResource()
+	if err := json2.Unmarshal(data, resource); err != nil {
+		return nil, err
+	}
+	return resource, nil
+}
+
+// resourceID is the id the resource is encoded with
+func resourceID(resource Resource) string {
+	if raw, ok := resource.(RawResource); ok {
+		return raw.ID
+	}
+
+	data, err := json2.Marshal(resource)
+	if err != nil {
+		return ""
+	}
+	var identity resourceIdentity
+	if err := json2.Unmarshal(data, &identity); err != nil {
+		return ""
+	}
+	return identity.ID
+}
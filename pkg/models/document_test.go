@@ -0,0 +1,118 @@
+// +build unit
+
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const documentWithIncluded = `{
+  "data": {
+    "type": "accounts",
+    "id": "48e51a61-29e2-44e6-a97d-4bcf3bda92fc",
+    "organisation_id": "4f8deb65-3755-4252-a495-9660d00c26a5",
+    "version": 0,
+    "attributes": {"country": "GB", "name": ["John", "Doe"]},
+    "relationships": {
+      "master_account": {"data": [{"type": "accounts", "id": "a52d13a4-f435-4c00-cfad-f5e7ac5972df"}]},
+      "account_events": {"data": [{"type": "account_events", "id": "c1023677-70ee-417a-9a6a-e211241f1e9c"}]}
+    }
+  },
+  "links": {"self": "/v1/organisation/accounts/48e51a61-29e2-44e6-a97d-4bcf3bda92fc"},
+  "meta": {"count": 1},
+  "included": [
+    {
+      "type": "accounts",
+      "id": "a52d13a4-f435-4c00-cfad-f5e7ac5972df",
+      "organisation_id": "4f8deb65-3755-4252-a495-9660d00c26a5",
+      "attributes": {"country": "GB", "name": ["Master"]}
+    },
+    {"type": "account_events", "id": "c1023677-70ee-417a-9a6a-e211241f1e9c", "attributes": {"event": "created"}}
+  ]
+}`
+
+func TestDocumentDeserialization(t *testing.T) {
+	document, err := DeserializeDocument([]byte(documentWithIncluded))
+	assert.NoError(t, err)
+
+	resource, ok := document.Resource()
+	assert.True(t, ok)
+	account := resource.(*OrganisationAccount)
+	assert.Equal(t, "48e51a61-29e2-44e6-a97d-4bcf3bda92fc", account.ID)
+	assert.Equal(t, "/v1/organisation/accounts/48e51a61-29e2-44e6-a97d-4bcf3bda92fc", document.Links.Self)
+	assert.Equal(t, "1", string(document.Meta["count"]))
+
+	master := account.Relationships.MasterAccount.Data[0]
+	included, ok := document.IncludedResource(master.Type, master.ID)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"Master"}, included.(*OrganisationAccount).Attributes.Name)
+
+	event := account.Relationships.AccountEvents.Data[0]
+	included, ok = document.IncludedResource(event.Type, event.ID)
+	assert.True(t, ok)
+	assert.Equal(t, RawResource{
+		Type: "account_events",
+		ID:   "c1023677-70ee-417a-9a6a-e211241f1e9c",
+		Raw:  []byte(`{"type": "account_events", "id": "c1023677-70ee-417a-9a6a-e211241f1e9c", "attributes": {"event": "created"}}`),
+	}, included)
+
+	_, ok = document.IncludedResource("accounts", "unknown")
+	assert.False(t, ok)
+}
+
+func TestDocumentRoundTrip(t *testing.T) {
+	document, err := DeserializeDocument([]byte(documentWithIncluded))
+	assert.NoError(t, err)
+
+	data, err := document.Serialize()
+	assert.NoError(t, err)
+	roundTripped, err := DeserializeDocument(data)
+	assert.NoError(t, err)
+	assert.Equal(t, document.Data, roundTripped.Data)
+	assert.Equal(t, document.Links, roundTripped.Links)
+	assert.Equal(t, document.Meta, roundTripped.Meta)
+	assert.Len(t, roundTripped.Included, 2)
+	assert.Equal(t, document.Included[0], roundTripped.Included[0])
+	assert.JSONEq(t, string(document.Included[1].(RawResource).Raw), string(roundTripped.Included[1].(RawResource).Raw))
+}
+
+func TestCollectionDocumentSerialization(t *testing.T) {
+	data, err := NewCollectionDocument(nil).Serialize()
+	assert.NoError(t, err)
+	assert.Equal(t, "{\n  \"data\": []\n}", string(data))
+
+	document, err := DeserializeDocument(data)
+	assert.NoError(t, err)
+	assert.True(t, document.Many)
+	assert.Empty(t, document.Data)
+	_, ok := document.Resource()
+	assert.False(t, ok)
+}
+
+func TestDocumentOfUntypedData(t *testing.T) {
+	document, err := DeserializeDocumentOf([]byte(`{"data": [{"id": "1"}, {"id": "2"}]}`), newAccountResource)
+	assert.NoError(t, err)
+	assert.True(t, document.Many)
+	assert.Len(t, document.Data, 2)
+	assert.Equal(t, "2", document.Data[1].(*OrganisationAccount).ID)
+}
+
+func TestErrorDocument(t *testing.T) {
+	payload := `{"errors": [{"status": "404", "title": "Not Found", "source": {"pointer": "/data/id"}}]}`
+
+	document, err := DeserializeDocument([]byte(payload))
+	assert.NoError(t, err)
+	assert.Empty(t, document.Data)
+	assert.Equal(t, []ErrorObject{{Status: "404", Title: "Not Found", Source: &ErrorSource{Pointer: "/data/id"}}}, document.Errors)
+
+	data, err := document.Serialize()
+	assert.NoError(t, err)
+	assert.JSONEq(t, payload, string(data))
+}
+
+func TestDocumentFailsOnInvalidJson(t *testing.T) {
+	_, err := DeserializeDocument([]byte(`{"data": [}`))
+	assert.EqualError(t, err, "Error - invalid character '}' looking for beginning of value")
+}
@@ -0,0 +1,324 @@
+package models
+
+import (
+	json2 "encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ErrUnknownField is wrapped by the error DeserializeAccountJsonStrict returns
+var ErrUnknownField = errors.New("unknown field")
+
+// the same fields without the json methods, so they can be used
+// to do the default encoding without recursing forever
+type organisationAccountFields OrganisationAccount
+type organisationAccountAttributeFields OrganisationAccountAttributes
+
+func (account OrganisationAccount) MarshalJSON() ([]byte, error) {
+	data, err := json2.Marshal(organisationAccountFields(account))
+	if err != nil {
+		return nil, err
+	}
+	return appendExtensions(data, account.Extensions)
+}
+
+func (account *OrganisationAccount) UnmarshalJSON(data []byte) error {
+	var fields organisationAccountFields
+	if err := json2.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	extensions, err := unknownFields(data, reflect.TypeOf(fields))
+	if err != nil {
+		return err
+	}
+
+	*account = OrganisationAccount(fields)
+	account.Extensions = extensions
+	return nil
+}
+
+func (attributes OrganisationAccountAttributes) MarshalJSON() ([]byte, error) {
+	data, err := json2.Marshal(organisationAccountAttributeFields(attributes))
+	if err != nil {
+		return nil, err
+	}
+	return appendExtensions(data, attributes.Extensions)
+}
+
+func (attributes *OrganisationAccountAttributes) UnmarshalJSON(data []byte) error {
+	var fields organisationAccountAttributeFields
+	if err := json2.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	extensions, err := unknownFields(data, reflect.TypeOf(fields))
+	if err != nil {
+		return err
+	}
+
+	*attributes = OrganisationAccountAttributes(fields)
+	attributes.Extensions = extensions
+	return nil
+}
+
+// the nested objects keep their unknown fields too, so none are lost when an
+// account is sent back to the api
+type userDefinedDataFields UserDefinedData
+type privateIdentificationFields PrivateIdentification
+type organisationIdentificationFields OrganisationIdentification
+type organisationActorFields OrganisationActor
+type accountRelationshipsFields AccountRelationships
+type relationshipFields Relationship
+type resourceIdentifierFields ResourceIdentifier
+
+func (userData UserDefinedData) MarshalJSON() ([]byte, error) {
+	return marshalWithExtensions(userDefinedDataFields(userData), userData.Extensions)
+}
+
+func (userData *UserDefinedData) UnmarshalJSON(data []byte) error {
+	var fields userDefinedDataFields
+	extensions, err := unmarshalWithExtensions(data, &fields)
+	if err != nil {
+		return err
+	}
+
+	*userData = UserDefinedData(fields)
+	userData.Extensions = extensions
+	return nil
+}
+
+func (identification PrivateIdentification) MarshalJSON() ([]byte, error) {
+	return marshalWithExtensions(privateIdentificationFields(identification), identification.Extensions)
+}
+
+func (identification *PrivateIdentification) UnmarshalJSON(data []byte) error {
+	var fields privateIdentificationFields
+	extensions, err := unmarshalWithExtensions(data, &fields)
+	if err != nil {
+		return err
+	}
+
+	*identification = PrivateIdentification(fields)
+	identification.Extensions = extensions
+	return nil
+}
+
+func (identification OrganisationIdentification) MarshalJSON() ([]byte, error) {
+	return marshalWithExtensions(organisationIdentificationFields(identification), identification.Extensions)
+}
+
+func (identification *OrganisationIdentification) UnmarshalJSON(data []byte) error {
+	var fields organisationIdentificationFields
+	extensions, err := unmarshalWithExtensions(data, &fields)
+	if err != nil {
+		return err
+	}
+
+	*identification = OrganisationIdentification(fields)
+	identification.Extensions = extensions
+	return nil
+}
+
+func (actor OrganisationActor) MarshalJSON() ([]byte, error) {
+	return marshalWithExtensions(organisationActorFields(actor), actor.Extensions)
+}
+
+func (actor *OrganisationActor) UnmarshalJSON(data []byte) error {
+	var fields organisationActorFields
+	extensions, err := unmarshalWithExtensions(data, &fields)
+	if err != nil {
+		return err
+	}
+
+	*actor = OrganisationActor(fields)
+	actor.Extensions = extensions
+	return nil
+}
+
+func (relationships AccountRelationships) MarshalJSON() ([]byte, error) {
+	return marshalWithExtensions(accountRelationshipsFields(relationships), relationships.Extensions)
+}
+
+func (relationships *AccountRelationships) UnmarshalJSON(data []byte) error {
+	var fields accountRelationshipsFields
+	extensions, err := unmarshalWithExtensions(data, &fields)
+	if err != nil {
+		return err
+	}
+
+	*relationships = AccountRelationships(fields)
+	relationships.Extensions = extensions
+	return nil
+}
+
+func (relationship Relationship) MarshalJSON() ([]byte, error) {
+	return marshalWithExtensions(relationshipFields(relationship), relationship.Extensions)
+}
+
+func (relationship *Relationship) UnmarshalJSON(data []byte) error {
+	var fields relationshipFields
+	extensions, err := unmarshalWithExtensions(data, &fields)
+	if err != nil {
+		return err
+	}
+
+	*relationship = Relationship(fields)
+	relationship.Extensions = extensions
+	return nil
+}
+
+func (identifier ResourceIdentifier) MarshalJSON() ([]byte, error) {
+	return marshalWithExtensions(resourceIdentifierFields(identifier), identifier.Extensions)
+}
+
+func (identifier *ResourceIdentifier) UnmarshalJSON(data []byte) error {
+	var fields resourceIdentifierFields
+	extensions, err := unmarshalWithExtensions(data, &fields)
+	if err != nil {
+		return err
+	}
+
+	*identifier = ResourceIdentifier(fields)
+	identifier.Extensions = extensions
+	return nil
+}
+
+// marshalWithExtensions encodes fields, a struct without json methods, followed by extensions
+func marshalWithExtensions(fields interface{}, extensions map[string]json2.RawMessage) ([]byte, error) {
+	data, err := json2.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	return appendExtensions(data, extensions)
+}
+
+// unmarshalWithExtensions decodes data into fields, a pointer to a struct without
+// json methods, returning the keys that don't belong to any of its fields
+func unmarshalWithExtensions(data []byte, fields interface{}) (map[string]json2.RawMessage, error) {
+	if err := json2.Unmarshal(data, fields); err != nil {
+		return nil, err
+	}
+	return unknownFields(data, reflect.TypeOf(fields).Elem())
+}
+
+// unknownFields picks out the keys of the json object that don't
+// belong to a field of the struct type, nil if there aren't any
+func unknownFields(data []byte, structType reflect.Type) (map[string]json2.RawMessage, error) {
+	var values map[string]json2.RawMessage
+	if err := json2.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+
+	for name := range jsonFieldNames(structType) {
+		delete(values, name)
+	}
+	if len(values) == 0 {
+		return nil, nil
+	}
+	return values, nil
+}
+
+// appendExtensions adds the extensions to the end of the json object, in
+// name order, skipping any that would clash with the object's own fields
+func appendExtensions(data []byte, extensions map[string]json2.RawMessage) ([]byte, error) {
+	if len(extensions) == 0 {
+		return data, nil
+	}
+
+	var known map[string]json2.RawMessage
+	if err := json2.Unmarshal(data, &known); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(extensions))
+	for name := range extensions {
+		if _, ok := known[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	extended := append([]byte{}, data[:len(data)-1]...)
+	for i, name := range names {
+		if len(known) > 0 || i > 0 {
+			extended = append(extended, ',')
+		}
+		encodedName, err := json2.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+		value := extensions[name]
+		if len(value) == 0 {
+			value = json2.RawMessage("null")
+		}
+		extended = append(append(append(extended, encodedName...), ':'), value...)
+	}
+	return append(extended, '}'), nil
+}
+
+func jsonFieldNames(structType reflect.Type) map[string]bool {
+	names := map[string]bool{}
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" || field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		names[name] = true
+	}
+	return names
+}
+
+// unknownFieldPaths lists the paths, e.g. "data.attributes.private_identification.x",
+// of every key in the json that doesn't belong to a field of valueType, looking into
+// nested objects and lists of objects. The keys of an object are listed before
+// those of the objects in it
+func unknownFieldPaths(path string, data json2.RawMessage, valueType reflect.Type) []string {
+	for valueType.Kind() == reflect.Ptr {
+		valueType = valueType.Elem()
+	}
+
+	paths := []string{}
+	switch valueType.Kind() {
+	case reflect.Struct:
+		// values that aren't objects, e.g. times, have no keys to check
+		var values map[string]json2.RawMessage
+		if err := json2.Unmarshal(data, &values); err != nil {
+			return paths
+		}
+
+		known := jsonFieldNames(valueType)
+		unknown := []string{}
+		for name := range values {
+			if !known[name] {
+				unknown = append(unknown, path+"."+name)
+			}
+		}
+		sort.Strings(unknown)
+		paths = append(paths, unknown...)
+
+		for i := 0; i < valueType.NumField(); i++ {
+			field := valueType.Field(i)
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if value, ok := values[name]; ok && known[name] {
+				paths = append(paths, unknownFieldPaths(path+"."+name, value, field.Type)...)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		var items []json2.RawMessage
+		if err := json2.Unmarshal(data, &items); err != nil {
+			return paths
+		}
+		for i, item := range items {
+			paths = append(paths, unknownFieldPaths(fmt.Sprintf("%s[%d]", path, i), item, valueType.Elem())...)
+		}
+	}
+	return paths
+}
@@ -0,0 +1,173 @@
+// +build unit
+
+package models
+
+import (
+	json2 "encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const accountWithUnknownFields = `{
+  "data": {
+    "type": "accounts",
+    "id": "48e51a61-29e2-44e6-a97d-4bcf3bda92fc",
+    "organisation_id": "4f8deb65-3755-4252-a495-9660d00c26a5",
+    "version": 1,
+    "future_field": {"nested": [1, 2]},
+    "attributes": {
+      "country": "GB",
+      "customer_id": "",
+      "name": ["John", "Doe"],
+      "account_classification": "Personal",
+      "joint_account": false,
+      "account_matching_opt_out": false,
+      "secondary_identification": "",
+      "switched": false,
+      "status": "pending",
+      "new_attribute": "kept",
+      "another_attribute": 12
+    }
+  }
+}`
+
+func TestUnknownFieldsAreCaptured(t *testing.T) {
+	t.Parallel()
+
+	account, err := DeserializeAccountJson([]byte(accountWithUnknownFields))
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[string]json2.RawMessage{"future_field": json2.RawMessage(`{"nested": [1, 2]}`)}, account.Extensions)
+	assert.Equal(t, map[string]json2.RawMessage{
+		"new_attribute":     json2.RawMessage(`"kept"`),
+		"another_attribute": json2.RawMessage(`12`),
+	}, account.Attributes.Extensions)
+	assert.Equal(t, "GB", account.Attributes.Country)
+	assert.Equal(t, 1, account.Version)
+}
+
+func TestUnknownFieldsRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	account, err := DeserializeAccountJson([]byte(accountWithUnknownFields))
+	assert.NoError(t, err)
+
+	payload, err := account.Serialize()
+	assert.NoError(t, err)
+	assert.JSONEq(t, accountWithUnknownFields, string(payload))
+}
+
+func TestKnownFieldsHaveNoExtensions(t *testing.T) {
+	t.Parallel()
+
+	account, err := DeserializeAccountJson([]byte(`{"data": {"id": "1", "attributes": {"country": "GB"}}}`))
+	assert.NoError(t, err)
+	assert.Nil(t, account.Extensions)
+	assert.Nil(t, account.Attributes.Extensions)
+}
+
+func TestExtensionsCantOverrideFields(t *testing.T) {
+	t.Parallel()
+
+	account := OrganisationAccount{
+		ID: "1",
+		Attributes: OrganisationAccountAttributes{
+			Country:    "GB",
+			Extensions: map[string]json2.RawMessage{"country": json2.RawMessage(`"FR"`), "extra": json2.RawMessage(`true`)},
+		},
+	}
+
+	data, err := json2.Marshal(account.Attributes)
+	assert.NoError(t, err)
+
+	var values map[string]interface{}
+	assert.NoError(t, json2.Unmarshal(data, &values))
+	assert.Equal(t, "GB", values["country"])
+	assert.Equal(t, true, values["extra"])
+}
+
+func TestPatchSendsChangedExtensions(t *testing.T) {
+	t.Parallel()
+
+	original, err := DeserializeAccountJson([]byte(accountWithUnknownFields))
+	assert.NoError(t, err)
+	updated, err := DeserializeAccountJson([]byte(accountWithUnknownFields))
+	assert.NoError(t, err)
+	updated.Attributes.Extensions["new_attribute"] = json2.RawMessage(`"changed"`)
+
+	payload, err := updated.SerializePatch(original)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"data": {"type": "accounts", "id": "48e51a61-29e2-44e6-a97d-4bcf3bda92fc", "version": 1, "attributes": {"new_attribute": "changed"}}}`, string(payload))
+}
+
+func TestStrictDeserializationRejectsUnknownFields(t *testing.T) {
+	t.Parallel()
+
+	_, err := DeserializeAccountJsonStrict([]byte(accountWithUnknownFields))
+	assert.True(t, errors.Is(err, ErrUnknownField))
+	assert.EqualError(t, err, "Error - unknown field: data.future_field, data.attributes.another_attribute, data.attributes.new_attribute")
+
+	account, err := DeserializeAccountJsonStrict([]byte(`{"data": {"id": "1", "attributes": {"country": "GB"}}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "GB", account.Attributes.Country)
+}
+
+const accountWithNestedUnknownFields = `{
+  "data": {
+    "type": "accounts",
+    "id": "1",
+    "attributes": {
+      "country": "GB",
+      "private_identification": {"birth_date": "2017-07-23", "eye_colour": "blue"},
+      "organisation_identification": {"actors": [{"name": ["Jane"]}, {"name": ["John"], "role": "director"}]}
+    },
+    "relationships": {
+      "master_account": {"data": [{"type": "accounts", "id": "2", "version": 1}]},
+      "account_owner": {"data": []}
+    }
+  }
+}`
+
+func TestNestedUnknownFieldsRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	account, err := DeserializeAccountJson([]byte(accountWithNestedUnknownFields))
+	assert.NoError(t, err)
+	assert.Equal(t, json2.RawMessage(`"blue"`), account.Attributes.PrivateIdentification.Extensions["eye_colour"])
+	assert.Nil(t, account.Attributes.OrganisationIdentification.Actors[0].Extensions)
+
+	payload, err := account.Serialize()
+	assert.NoError(t, err)
+	var sent struct {
+		Data struct {
+			Attributes    map[string]json2.RawMessage `json:"attributes"`
+			Relationships json2.RawMessage            `json:"relationships"`
+		} `json:"data"`
+	}
+	assert.NoError(t, json2.Unmarshal(payload, &sent))
+	assert.JSONEq(t, `{"birth_date": "2017-07-23", "eye_colour": "blue"}`, string(sent.Data.Attributes["private_identification"]))
+	assert.JSONEq(t, `{"actors": [{"name": ["Jane"]}, {"name": ["John"], "role": "director"}]}`, string(sent.Data.Attributes["organisation_identification"]))
+	assert.JSONEq(t, `{
+  "master_account": {"data": [{"type": "accounts", "id": "2", "version": 1}]},
+  "account_owner": {"data": []}
+}`, string(sent.Data.Relationships))
+
+	// they aren't a difference to reconcile
+	known := account
+	known.Attributes.PrivateIdentification = &PrivateIdentification{BirthDate: "2017-07-23"}
+	assert.Empty(t, known.Diff(account))
+}
+
+func TestStrictDeserializationRejectsNestedUnknownFields(t *testing.T) {
+	t.Parallel()
+
+	_, err := DeserializeAccountJsonStrict([]byte(accountWithNestedUnknownFields))
+	assert.True(t, errors.Is(err, ErrUnknownField))
+	assert.EqualError(t, err, "Error - unknown field: "+
+		"data.attributes.private_identification.eye_colour, "+
+		"data.attributes.organisation_identification.actors[1].role, "+
+		"data.relationships.account_owner, "+
+		"data.relationships.master_account.data[0].version")
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	json2 "encoding/json"
+)
+
+// HealthStatus is whether the api is able to serve requests
+type HealthStatus string
+
+const (
+	HealthUp   HealthStatus = "up"
+	HealthDown HealthStatus = "down"
+)
+
+// Health is the api's response to a health check
+type Health struct {
+	Status HealthStatus `json:"status"`
+}
+
+// IsUp is true when the api reported itself as up
+func (health Health) IsUp() bool {
+	return health.Status == HealthUp
+}
+
+func DeserializeHealthJson(body []byte) (Health, error) {
+	var health Health
+	err := json2.Unmarshal(body, &health)
+	if err != nil {
+		return Health{}, FinanceApiError{Err: err}
+	}
+	return health, nil
+}
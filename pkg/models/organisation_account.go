@@ -2,32 +2,149 @@ package models
 
 import (
 	json2 "encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
 	"time"
 )
 
-type accountCreation struct {
-	Data OrganisationAccount `json:"data"`
+type accountPatchData struct {
+	Type       string                      `json:"type"`
+	ID         string                      `json:"id"`
+	Version    int                         `json:"version"`
+	Attributes map[string]json2.RawMessage `json:"attributes"`
+}
+
+func (accountPatchData) ResourceType() string {
+	return accountsType
+}
+
+// accountsType is the JSON:API type of OrganisationAccount
+const accountsType = "accounts"
+
+func init() {
+	RegisterResourceType(accountsType, newAccountResource)
+}
+
+func newAccountResource() Resource {
+	return &OrganisationAccount{}
+}
+
+// OrganisationAccountList is a single page of accounts along with the
+// links to the other pages
+type OrganisationAccountList struct {
+	Accounts []OrganisationAccount
+	Links    Links
 }
 
 type OrganisationAccountAttributes struct {
-	Country                 string   `json:"country"`
-	BaseCurrency            string   `json:"base_currency,omitempty"`
-	BankID                  string   `json:"bank_id,omitempty"`
-	BankIDCode              string   `json:"bank_id_code,omitempty"`
-	AccountNumber           string   `json:"account_number,omitempty"`
-	Bic                     string   `json:"bic,omitempty"`
-	Iban                    string   `json:"iban,omitempty"`
-	CustomerId              string   `json:"customer_id"`
-	Name                    []string `json:"name"`
-	AlternativeNames        []string `json:"alternative_names,omitempty"`
-	AccountClassification   string   `json:"account_classification"`
-	JointAccount            bool     `json:"joint_account"`
-	AccountMatchingOptOut   bool     `json:"account_matching_opt_out"`
-	SecondaryIdentification string   `json:"secondary_identification"`
-	Switched                bool     `json:"switched"`
-	Status                  string   `json:"status"`
+	Country                     string                      `json:"country"`
+	BaseCurrency                string                      `json:"base_currency,omitempty"`
+	BankID                      string                      `json:"bank_id,omitempty"`
+	BankIDCode                  string                      `json:"bank_id_code,omitempty"`
+	AccountNumber               string                      `json:"account_number,omitempty"`
+	Bic                         string                      `json:"bic,omitempty"`
+	Iban                        string                      `json:"iban,omitempty"`
+	CustomerId                  string                      `json:"customer_id"`
+	Name                        []string                    `json:"name"`
+	AlternativeNames            []string                    `json:"alternative_names,omitempty"`
+	AccountClassification       string                      `json:"account_classification"`
+	JointAccount                bool                        `json:"joint_account"`
+	AccountMatchingOptOut       bool                        `json:"account_matching_opt_out"`
+	SecondaryIdentification     string                      `json:"secondary_identification"`
+	Switched                    bool                        `json:"switched"`
+	Status                      string                      `json:"status"`
+	StatusReason                string                      `json:"status_reason,omitempty"`
+	BankAccountName             string                      `json:"bank_account_name,omitempty"`
+	AlternativeBankAccountNames []string                    `json:"alternative_bank_account_names,omitempty"`
+	AcceptanceQualifier         string                      `json:"acceptance_qualifier,omitempty"`
+	ProcessingService           string                      `json:"processing_service,omitempty"`
+	UserDefinedInformation      string                      `json:"user_defined_information,omitempty"`
+	UserDefinedData             []UserDefinedData           `json:"user_defined_data,omitempty"`
+	ValidationType              string                      `json:"validation_type,omitempty"`
+	ReferenceMask               string                      `json:"reference_mask,omitempty"`
+	NameMatchingStatus          string                      `json:"name_matching_status,omitempty"`
+	PrivateIdentification       *PrivateIdentification      `json:"private_identification,omitempty"`
+	OrganisationIdentification  *OrganisationIdentification `json:"organisation_identification,omitempty"`
+	// Extensions holds attributes the api sent that aren't known to this
+	// version of the client, they're sent back as is when serialised
+	Extensions map[string]json2.RawMessage `json:"-"`
+}
+
+// UserDefinedData is a key value pair stored against the account for the caller's own use
+type UserDefinedData struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	// Extensions are the unknown fields, see OrganisationAccount.Extensions
+	Extensions map[string]json2.RawMessage `json:"-"`
+}
+
+// PrivateIdentification identifies the person holding a Personal account
+type PrivateIdentification struct {
+	Title                string   `json:"title,omitempty"`
+	FirstName            string   `json:"first_name,omitempty"`
+	LastName             string   `json:"last_name,omitempty"`
+	BirthDate            string   `json:"birth_date,omitempty"`
+	BirthCountry         string   `json:"birth_country,omitempty"`
+	Identification       string   `json:"identification,omitempty"`
+	IdentificationIssuer string   `json:"identification_issuer,omitempty"`
+	IdentificationScheme string   `json:"identification_scheme,omitempty"`
+	Address              []string `json:"address,omitempty"`
+	City                 string   `json:"city,omitempty"`
+	Country              string   `json:"country,omitempty"`
+	// Extensions are the unknown fields, see OrganisationAccount.Extensions
+	Extensions map[string]json2.RawMessage `json:"-"`
+}
+
+// OrganisationIdentification identifies the organisation holding a Business account
+type OrganisationIdentification struct {
+	Name                 []string            `json:"name,omitempty"`
+	Identification       string              `json:"identification,omitempty"`
+	IdentificationIssuer string              `json:"identification_issuer,omitempty"`
+	IdentificationScheme string              `json:"identification_scheme,omitempty"`
+	RegistrationNumber   string              `json:"registration_number,omitempty"`
+	TaxResidency         string              `json:"tax_residency,omitempty"`
+	Representative       *OrganisationActor  `json:"representative,omitempty"`
+	Actors               []OrganisationActor `json:"actors,omitempty"`
+	Address              []string            `json:"address,omitempty"`
+	City                 string              `json:"city,omitempty"`
+	Country              string              `json:"country,omitempty"`
+	// Extensions are the unknown fields, see OrganisationAccount.Extensions
+	Extensions map[string]json2.RawMessage `json:"-"`
+}
+
+// OrganisationActor is a person acting on behalf of an organisation
+type OrganisationActor struct {
+	Name      []string `json:"name,omitempty"`
+	BirthDate string   `json:"birth_date,omitempty"`
+	Residency string   `json:"residency,omitempty"`
+	// Extensions are the unknown fields, see OrganisationAccount.Extensions
+	Extensions map[string]json2.RawMessage `json:"-"`
+}
+
+// AccountRelationships links an account to the other resources it's related to
+type AccountRelationships struct {
+	MasterAccount *Relationship `json:"master_account,omitempty"`
+	AccountEvents *Relationship `json:"account_events,omitempty"`
+	// Extensions are the unknown fields, see OrganisationAccount.Extensions
+	Extensions map[string]json2.RawMessage `json:"-"`
 }
 
+// Relationship is the JSON:API identifiers of the resources on the other side of a relationship
+type Relationship struct {
+	Data []ResourceIdentifier `json:"data"`
+	// Extensions are the unknown fields, see OrganisationAccount.Extensions
+	Extensions map[string]json2.RawMessage `json:"-"`
+}
+
+// ResourceIdentifier identifies a single JSON:API resource
+type ResourceIdentifier struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+	// Extensions are the unknown fields, see OrganisationAccount.Extensions
+	Extensions map[string]json2.RawMessage `json:"-"`
+}
 type OrganisationAccount struct {
 	Type           string                        `json:"type"`
 	ID             string                        `json:"id"`
@@ -36,24 +153,212 @@ type OrganisationAccount struct {
 	CreatedOn      *time.Time                    `json:"created_on,omitempty"`
 	ModifiedOn     *time.Time                    `json:"modified_on,omitempty"`
 	Attributes     OrganisationAccountAttributes `json:"attributes"`
+	Relationships  *AccountRelationships         `json:"relationships,omitempty"`
+	// Extensions holds fields the api sent that aren't known to this
+	// version of the client, they're sent back as is when serialised
+	Extensions map[string]json2.RawMessage `json:"-"`
+}
+
+func (account OrganisationAccount) ResourceType() string {
+	return accountsType
 }
 
 func (account *OrganisationAccount) Serialize() ([]byte, error) {
-	newAccount := accountCreation{Data: *account}
-	newAccount.Data.Type = "accounts"
-	data, err := json2.MarshalIndent(newAccount, "", "  ")
+	newAccount := *account
+	newAccount.Type = accountsType
+	newAccount.Attributes.Bic = NormalizeBIC(account.Attributes.Bic)
+	return NewDocument(newAccount).Serialize()
+}
+
+// SerializePatch creates the body of a PATCH request that changes original into
+// account. Only the attributes that differ are sent, attributes that have been
+// cleared are sent as null, along with original's version so the api can reject
+// the change if the account has been modified since original was fetched
+func (account *OrganisationAccount) SerializePatch(original OrganisationAccount) ([]byte, error) {
+	// the bic is normalised as it is by Serialize, so an unchanged one isn't sent
+	updatedAttributes, originalAttributes := account.Attributes, original.Attributes
+	updatedAttributes.Bic = NormalizeBIC(updatedAttributes.Bic)
+	originalAttributes.Bic = NormalizeBIC(originalAttributes.Bic)
+
+	attributes, err := attributeValues(updatedAttributes)
+	if err != nil {
+		return nil, FinanceApiError{Err: err}
+	}
+	originalValues, err := attributeValues(originalAttributes)
 	if err != nil {
 		return nil, FinanceApiError{Err: err}
 	}
-	return data, nil
+
+	changed := map[string]json2.RawMessage{}
+	for name, value := range attributes {
+		if string(originalValues[name]) != string(value) {
+			changed[name] = value
+		}
+	}
+	for name := range originalValues {
+		if _, ok := attributes[name]; !ok {
+			changed[name] = json2.RawMessage("null")
+		}
+	}
+
+	return NewDocument(accountPatchData{
+		Type:       accountsType,
+		ID:         original.ID,
+		Version:    original.Version,
+		Attributes: changed,
+	}).Serialize()
+}
+
+func attributeValues(attributes OrganisationAccountAttributes) (map[string]json2.RawMessage, error) {
+	data, err := json2.Marshal(attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]json2.RawMessage{}
+	err = json2.Unmarshal(data, &values)
+	return values, err
 }
 
 func DeserializeAccountJson(body []byte) (OrganisationAccount, error) {
-	var savedAccount accountCreation
-	err := json2.Unmarshal(body, &savedAccount)
+	document, err := DeserializeDocumentOf(body, newAccountResource)
 	if err != nil {
+		return OrganisationAccount{}, err
+	}
+
+	resource, ok := document.Resource()
+	if !ok {
+		return OrganisationAccount{}, FinanceApiError{Err: errors.New("document's data isn't a single account")}
+	}
+	return *resource.(*OrganisationAccount), nil
+}
+
+// DeserializeAccountJsonStrict behaves like DeserializeAccountJson but fails with an
+// error wrapping ErrUnknownField when the account, or any object in it such as its
+// attributes, private_identification or relationships, has fields this version of
+// the client doesn't know about, e.g. for contract testing against the api
+func DeserializeAccountJsonStrict(body []byte) (OrganisationAccount, error) {
+	account, err := DeserializeAccountJson(body)
+	if err != nil {
+		return OrganisationAccount{}, err
+	}
+
+	var document struct {
+		Data json2.RawMessage `json:"data"`
+	}
+	if err := json2.Unmarshal(body, &document); err != nil {
 		return OrganisationAccount{}, FinanceApiError{Err: err}
 	}
 
-	return savedAccount.Data, nil
+	unknown := unknownFieldPaths("data", document.Data, reflect.TypeOf(account))
+	if len(unknown) > 0 {
+		return OrganisationAccount{}, FinanceApiError{Err: fmt.Errorf("%w: %s", ErrUnknownField, strings.Join(unknown, ", "))}
+	}
+	return account, nil
+}
+
+func DeserializeAccountListJson(body []byte) (OrganisationAccountList, error) {
+	document, err := DeserializeDocumentOf(body, newAccountResource)
+	if err != nil {
+		return OrganisationAccountList{}, err
+	}
+
+	list := OrganisationAccountList{Accounts: []OrganisationAccount{}}
+	for _, resource := range document.Data {
+		list.Accounts = append(list.Accounts, *resource.(*OrganisationAccount))
+	}
+	if document.Links != nil {
+		list.Links = *document.Links
+	}
+	return list, nil
+}
+
+// Diff lists the fields, by their json names, that differ between the two accounts.
+// Fields set by the api (type, version, created_on and modified_on) and unknown
+// fields kept in Extensions are ignored, and empty lists are treated the same as
+// missing ones. The bic is compared as it's sent, normalised. Of the relationships
+// only master_account is compared, account_events are added by the api
+func (account OrganisationAccount) Diff(other OrganisationAccount) []string {
+	differences := []string{}
+	if account.ID != other.ID {
+		differences = append(differences, "id")
+	}
+	if account.OrganisationID != other.OrganisationID {
+		differences = append(differences, "organisation_id")
+	}
+
+	attributes, otherAttributes := account.Attributes, other.Attributes
+	attributes.Bic = NormalizeBIC(attributes.Bic)
+	otherAttributes.Bic = NormalizeBIC(otherAttributes.Bic)
+	differences = append(differences, diffFields("attributes", reflect.ValueOf(attributes), reflect.ValueOf(otherAttributes))...)
+
+	if !equalValues(reflect.ValueOf(account.masterAccount()), reflect.ValueOf(other.masterAccount())) {
+		differences = append(differences, "relationships.master_account")
+	}
+	return differences
+}
+
+// masterAccount is the identifiers of the account's master account, nil if it has none
+func (account OrganisationAccount) masterAccount() []ResourceIdentifier {
+	if account.Relationships == nil || account.Relationships.MasterAccount == nil {
+		return nil
+	}
+	return account.Relationships.MasterAccount.Data
+}
+
+func diffFields(prefix string, value reflect.Value, other reflect.Value) []string {
+	differences := []string{}
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" || field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		if !equalValues(value.Field(i), other.Field(i)) {
+			differences = append(differences, prefix+"."+name)
+		}
+	}
+	return differences
+}
+
+// equalValues compares the values, treating empty lists as missing ones and
+// ignoring the Extensions of any objects in them
+func equalValues(value reflect.Value, other reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Ptr:
+		if value.IsNil() || other.IsNil() {
+			return value.IsNil() == other.IsNil()
+		}
+		return equalValues(value.Elem(), other.Elem())
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+			if field.Name == "Extensions" || field.PkgPath != "" {
+				continue
+			}
+			if !equalValues(value.Field(i), other.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Slice:
+		if value.Len() != other.Len() {
+			return false
+		}
+		for i := 0; i < value.Len(); i++ {
+			if !equalValues(value.Index(i), other.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Map:
+		if value.Len() == 0 && other.Len() == 0 {
+			return true
+		}
+	}
+	return reflect.DeepEqual(value.Interface(), other.Interface())
 }
@@ -242,3 +242,274 @@ func TestOrganisationAccountFailOnIncompleteJson(t *testing.T) {
 	assert.EqualError(t, err, "Error - invalid character '\\n' in string literal")
 
 }
+
+func TestOrganisationAccountFailOnMissingAccount(t *testing.T) {
+	for _, payload := range []string{`{}`, `{"data": null}`, `{"data": [{"type": "accounts", "id": "1"}]}`} {
+		_, err := DeserializeAccountJson([]byte(payload))
+		assert.EqualError(t, err, "Error - document's data isn't a single account", payload)
+	}
+}
+
+func TestOrganisationAccountListDeserialization(t *testing.T) {
+	payload := `{
+  "data": [
+    {
+      "type": "accounts",
+      "id": "48e51a61-29e2-44e6-a97d-4bcf3bda92fc",
+      "organisation_id": "4f8deb65-3755-4252-a495-9660d00c26a5",
+      "version": 0,
+      "attributes": {"country": "GB", "name": ["John", "Doe"]}
+    },
+    {
+      "type": "accounts",
+      "id": "26628e05-0bbd-4de2-8da4-7d95bcd15ae0",
+      "organisation_id": "e13d2e6c-874a-4356-b35a-3e32dab2c34e",
+      "version": 3,
+      "attributes": {"country": "FR", "name": ["Jane", "Doe"]}
+    }
+  ],
+  "links": {
+    "first": "/v1/organisation/accounts?page%5Bnumber%5D=first",
+    "last": "/v1/organisation/accounts?page%5Bnumber%5D=last",
+    "self": "/v1/organisation/accounts"
+  }
+}`
+
+	list, err := DeserializeAccountListJson([]byte(payload))
+	assert.NoError(t, err)
+
+	assert.Len(t, list.Accounts, 2)
+	assert.Equal(t, "48e51a61-29e2-44e6-a97d-4bcf3bda92fc", list.Accounts[0].ID)
+	assert.Equal(t, "GB", list.Accounts[0].Attributes.Country)
+	assert.Equal(t, "26628e05-0bbd-4de2-8da4-7d95bcd15ae0", list.Accounts[1].ID)
+	assert.Equal(t, 3, list.Accounts[1].Version)
+	assert.Equal(t, "FR", list.Accounts[1].Attributes.Country)
+
+	assert.Equal(t, "/v1/organisation/accounts?page%5Bnumber%5D=first", list.Links.First)
+	assert.Equal(t, "/v1/organisation/accounts?page%5Bnumber%5D=last", list.Links.Last)
+	assert.Equal(t, "/v1/organisation/accounts", list.Links.Self)
+	assert.Equal(t, "", list.Links.Next)
+	assert.Equal(t, "", list.Links.Prev)
+}
+
+func TestOrganisationAccountEmptyListDeserialization(t *testing.T) {
+	list, err := DeserializeAccountListJson([]byte(`{"data": []}`))
+	assert.NoError(t, err)
+	assert.NotNil(t, list.Accounts)
+	assert.Empty(t, list.Accounts)
+}
+
+func TestOrganisationAccountDiff(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	account := OrganisationAccount{
+		ID:             "48e51a61-29e2-44e6-a97d-4bcf3bda92fc",
+		OrganisationID: "4f8deb65-3755-4252-a495-9660d00c26a5",
+		Attributes: OrganisationAccountAttributes{
+			Country: "GB",
+			Name:    []string{"John", "Doe"},
+		},
+	}
+
+	saved := account
+	saved.Type = "accounts"
+	saved.Version = 3
+	saved.CreatedOn = &now
+	saved.ModifiedOn = &now
+	saved.Attributes.AlternativeNames = []string{}
+	assert.Empty(t, account.Diff(saved))
+
+	changed := saved
+	changed.OrganisationID = "e13d2e6c-874a-4356-b35a-3e32dab2c34e"
+	changed.Attributes.Name = []string{"Jane", "Doe"}
+	changed.Attributes.JointAccount = true
+	assert.Equal(t, []string{"organisation_id", "attributes.name", "attributes.joint_account"}, account.Diff(changed))
+}
+
+func TestOrganisationAccountDiffNormalisesBic(t *testing.T) {
+	t.Parallel()
+
+	account := OrganisationAccount{Attributes: OrganisationAccountAttributes{Country: "GB", Bic: " nwbk gb 22 "}}
+	saved := OrganisationAccount{Attributes: OrganisationAccountAttributes{Country: "GB", Bic: "NWBKGB22"}}
+	assert.Empty(t, account.Diff(saved))
+
+	saved.Attributes.Bic = "NWBKGB21"
+	assert.Equal(t, []string{"attributes.bic"}, account.Diff(saved))
+}
+
+func TestOrganisationAccountDiffRelationships(t *testing.T) {
+	t.Parallel()
+
+	master := &Relationship{Data: []ResourceIdentifier{{Type: "accounts", ID: "a52d13a4-f435-4c00-cfad-f5e7ac5972df"}}}
+	account := OrganisationAccount{Relationships: &AccountRelationships{MasterAccount: master}}
+
+	// account events are added by the api
+	saved := OrganisationAccount{Relationships: &AccountRelationships{
+		MasterAccount: master,
+		AccountEvents: &Relationship{Data: []ResourceIdentifier{{Type: "account_events", ID: "c1023677-70ee-417a-9a6a-e211241f1e9c"}}},
+	}}
+	assert.Empty(t, account.Diff(saved))
+	assert.Empty(t, OrganisationAccount{}.Diff(OrganisationAccount{Relationships: &AccountRelationships{}}))
+
+	saved.Relationships.MasterAccount = nil
+	assert.Equal(t, []string{"relationships.master_account"}, account.Diff(saved))
+}
+
+func TestOrganisationAccountPatchSerialization(t *testing.T) {
+	t.Parallel()
+
+	original := OrganisationAccount{
+		ID:             "48e51a61-29e2-44e6-a97d-4bcf3bda92fc",
+		OrganisationID: "4f8deb65-3755-4252-a495-9660d00c26a5",
+		Version:        2,
+		Attributes: OrganisationAccountAttributes{
+			Country:          "GB",
+			BankID:           "400302",
+			Name:             []string{"John", "Doe"},
+			AlternativeNames: []string{"J Dog"},
+			Status:           "pending",
+		},
+	}
+	updated := original
+	updated.Version = 7
+	updated.Attributes.Name = []string{"Jane", "Doe"}
+	updated.Attributes.AlternativeNames = nil
+	updated.Attributes.Switched = true
+
+	payload, err := updated.SerializePatch(original)
+	assert.NoError(t, err)
+
+	expectedPayload := `{
+  "data": {
+    "type": "accounts",
+    "id": "48e51a61-29e2-44e6-a97d-4bcf3bda92fc",
+    "version": 2,
+    "attributes": {
+      "alternative_names": null,
+      "name": [
+        "Jane",
+        "Doe"
+      ],
+      "switched": true
+    }
+  }
+}`
+
+	assert.Equal(t, expectedPayload, string(payload))
+}
+
+func TestOrganisationAccountPatchNormalisesBic(t *testing.T) {
+	t.Parallel()
+
+	original := OrganisationAccount{ID: "48e51a61-29e2-44e6-a97d-4bcf3bda92fc", Attributes: OrganisationAccountAttributes{Bic: "NWBKGB22"}}
+	updated := original
+	updated.Attributes.Bic = "nwbk gb 22"
+
+	payload, err := updated.SerializePatch(original)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(payload), "bic")
+
+	updated.Attributes.Bic = "nwbk gb 21"
+	payload, err = updated.SerializePatch(original)
+	assert.NoError(t, err)
+	assert.Contains(t, string(payload), `"bic": "NWBKGB21"`)
+}
+
+func TestOrganisationAccountFullSchemaRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	payload := `{
+  "data": {
+    "type": "accounts",
+    "id": "26628e05-0bbd-4de2-8da4-7d95bcd15ae0",
+    "organisation_id": "e13d2e6c-874a-4356-b35a-3e32dab2c34e",
+    "version": 2,
+    "created_on": "2021-06-14T19:51:27.813975019+01:00",
+    "modified_on": "2021-06-14T19:51:27.813975019+01:00",
+    "attributes": {
+      "country": "GB",
+      "base_currency": "GBP",
+      "bank_id": "400302",
+      "bank_id_code": "GBDSC",
+      "account_number": "10000004",
+      "bic": "NWBKGB42",
+      "iban": "GB28NWBK40030212764204",
+      "customer_id": "234",
+      "name": ["John", "Doe"],
+      "alternative_names": ["J Dog"],
+      "account_classification": "Business",
+      "joint_account": false,
+      "account_matching_opt_out": false,
+      "secondary_identification": "A1B2C3D4",
+      "switched": false,
+      "status": "confirmed",
+      "status_reason": "unspecified",
+      "bank_account_name": "John Doe Ltd",
+      "alternative_bank_account_names": ["JD Ltd"],
+      "acceptance_qualifier": "same_day",
+      "processing_service": "ABC Bank",
+      "user_defined_information": "Some information",
+      "user_defined_data": [{"key": "crm_id", "value": "1234"}],
+      "validation_type": "card",
+      "reference_mask": "############",
+      "name_matching_status": "supported",
+      "private_identification": {
+        "title": "Mr",
+        "first_name": "John",
+        "last_name": "Doe",
+        "birth_date": "2017-07-23",
+        "birth_country": "GB",
+        "identification": "13YH458762",
+        "identification_issuer": "HMRC",
+        "identification_scheme": "NINO",
+        "address": ["10 Avenue des Champs"],
+        "city": "London",
+        "country": "GB"
+      },
+      "organisation_identification": {
+        "name": ["John Doe Ltd"],
+        "identification": "123654",
+        "registration_number": "07654321",
+        "tax_residency": "GB",
+        "representative": {"name": ["Jane Doe"], "birth_date": "1970-01-01", "residency": "GB"},
+        "actors": [{"name": ["Jeff Page"], "birth_date": "1970-01-01", "residency": "GB"}],
+        "address": ["10 Avenue des Champs"],
+        "city": "London",
+        "country": "GB"
+      }
+    },
+    "relationships": {
+      "master_account": {
+        "data": [{"type": "accounts", "id": "a52d13a4-f435-4c00-cfad-f5e7ac5972df"}]
+      },
+      "account_events": {
+        "data": [{"type": "account_events", "id": "c1023677-70ee-417a-9a6a-e211241f1e9c"}]
+      }
+    }
+  }
+}`
+
+	account, err := DeserializeAccountJson([]byte(payload))
+	assert.NoError(t, err)
+
+	attributes := account.Attributes
+	assert.Equal(t, "John Doe Ltd", attributes.BankAccountName)
+	assert.Equal(t, []string{"JD Ltd"}, attributes.AlternativeBankAccountNames)
+	assert.Equal(t, "same_day", attributes.AcceptanceQualifier)
+	assert.Equal(t, "ABC Bank", attributes.ProcessingService)
+	assert.Equal(t, "Some information", attributes.UserDefinedInformation)
+	assert.Equal(t, []UserDefinedData{{Key: "crm_id", Value: "1234"}}, attributes.UserDefinedData)
+	assert.Equal(t, "supported", attributes.NameMatchingStatus)
+	assert.Equal(t, "NINO", attributes.PrivateIdentification.IdentificationScheme)
+	assert.Equal(t, []string{"10 Avenue des Champs"}, attributes.PrivateIdentification.Address)
+	assert.Equal(t, "07654321", attributes.OrganisationIdentification.RegistrationNumber)
+	assert.Equal(t, []string{"Jane Doe"}, attributes.OrganisationIdentification.Representative.Name)
+	assert.Equal(t, "Jeff Page", attributes.OrganisationIdentification.Actors[0].Name[0])
+	assert.Equal(t, "a52d13a4-f435-4c00-cfad-f5e7ac5972df", account.Relationships.MasterAccount.Data[0].ID)
+	assert.Equal(t, "account_events", account.Relationships.AccountEvents.Data[0].Type)
+
+	serialized, err := account.Serialize()
+	assert.NoError(t, err)
+	assert.JSONEq(t, payload, string(serialized))
+}
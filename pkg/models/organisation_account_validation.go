@@ -0,0 +1,110 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxNameLines            = 4
+	maxAlternativeNameLines = 3
+	maxNameLength           = 140
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+var accountClassifications = []string{"Personal", "Business"}
+
+var accountStatuses = []string{"pending", "confirmed", "failed"}
+
+// ValidationErrors is every problem found while validating a resource
+// before it's sent to the api
+type ValidationErrors []FieldError
+
+func (v ValidationErrors) Error() string {
+	messages := make([]string, len(v))
+	for i, fieldError := range v {
+		messages[i] = fieldError.Message
+	}
+	return fmt.Sprintf("Error - %s%s", validationFailureList, strings.Join(messages, "\n"))
+}
+
+// Is lets errors.Is match ValidationErrors against ErrValidation
+func (v ValidationErrors) Is(target error) bool {
+	return target == ErrValidation
+}
+
+func (v *ValidationErrors) add(field string, format string, args ...interface{}) {
+	message := fmt.Sprintf("%s in body %s", field, fmt.Sprintf(format, args...))
+	*v = append(*v, FieldError{Field: field, In: "body", Message: message})
+}
+
+// Validate checks the account is well formed before it's sent to the api,
+// returning ValidationErrors listing every problem found, or nil if there are none
+func (account *OrganisationAccount) Validate() error {
+	problems := ValidationErrors{}
+
+	validateUuid(&problems, "id", account.ID)
+	validateUuid(&problems, "organisation_id", account.OrganisationID)
+
+	attributes := account.Attributes
+	if attributes.Country == "" {
+		problems.add("attributes.country", "is required")
+	} else if !iso3166Countries[attributes.Country] {
+		problems.add("attributes.country", "should be an ISO 3166-1 country code")
+	}
+
+	if attributes.BaseCurrency != "" && !iso4217Currencies[attributes.BaseCurrency] {
+		problems.add("attributes.base_currency", "should be an ISO 4217 currency code")
+	}
+
+	if len(attributes.Name) == 0 || len(attributes.Name) > maxNameLines {
+		problems.add("attributes.name", "should have between 1 and %d items", maxNameLines)
+	}
+	validateNameLines(&problems, "attributes.name", attributes.Name)
+
+	if len(attributes.AlternativeNames) > maxAlternativeNameLines {
+		problems.add("attributes.alternative_names", "should have at most %d items", maxAlternativeNameLines)
+	}
+	validateNameLines(&problems, "attributes.alternative_names", attributes.AlternativeNames)
+
+	validateOneOf(&problems, "attributes.account_classification", attributes.AccountClassification, accountClassifications)
+	validateOneOf(&problems, "attributes.status", attributes.Status, accountStatuses)
+
+	if len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
+
+func validateUuid(problems *ValidationErrors, field string, value string) {
+	if value == "" {
+		problems.add(field, "is required")
+	} else if !uuidPattern.MatchString(value) {
+		problems.add(field, "should be a uuid")
+	}
+}
+
+func validateNameLines(problems *ValidationErrors, field string, lines []string) {
+	for i, line := range lines {
+		length := utf8.RuneCountInString(line)
+		if length == 0 || length > maxNameLength {
+			problems.add(fmt.Sprintf("%s.%d", field, i), "should be between 1 and %d characters long", maxNameLength)
+		}
+	}
+}
+
+// validateOneOf checks an optional value is one of those allowed
+func validateOneOf(problems *ValidationErrors, field string, value string, allowed []string) {
+	if value == "" {
+		return
+	}
+	for _, allowedValue := range allowed {
+		if value == allowedValue {
+			return
+		}
+	}
+	problems.add(field, "should be one of [%s]", strings.Join(allowed, " "))
+}
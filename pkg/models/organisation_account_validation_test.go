@@ -0,0 +1,84 @@
+// +build unit
+
+package models
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func validAccount() OrganisationAccount {
+	return OrganisationAccount{
+		ID:             "48e51a61-29e2-44e6-a97d-4bcf3bda92fc",
+		OrganisationID: "4f8deb65-3755-4252-a495-9660d00c26a5",
+		Attributes: OrganisationAccountAttributes{
+			AccountClassification: "Personal",
+			BaseCurrency:          "GBP",
+			Country:               "GB",
+			Name:                  []string{"John", "Doe"},
+			Status:                "pending",
+		},
+	}
+}
+
+func TestOrganisationAccountValidateValid(t *testing.T) {
+	t.Parallel()
+
+	account := validAccount()
+	assert.NoError(t, account.Validate())
+
+	account.Attributes.AccountClassification = ""
+	account.Attributes.BaseCurrency = ""
+	account.Attributes.Status = ""
+	assert.NoError(t, account.Validate())
+}
+
+func TestOrganisationAccountValidateListsEveryProblem(t *testing.T) {
+	t.Parallel()
+
+	account := validAccount()
+	account.ID = ""
+	account.OrganisationID = "not-a-uuid"
+	account.Attributes.Country = "XX"
+	account.Attributes.BaseCurrency = "POUNDS"
+	account.Attributes.Name = []string{"John", "", strings.Repeat("a", 141), "Doe", "Jr"}
+	account.Attributes.AccountClassification = "personal"
+	account.Attributes.Status = "closed"
+
+	err := account.Validate()
+
+	var problems ValidationErrors
+	assert.True(t, errors.As(err, &problems))
+	assert.True(t, errors.Is(err, ErrValidation))
+
+	fields := []string{}
+	for _, problem := range problems {
+		fields = append(fields, problem.Field)
+	}
+	assert.Equal(t, []string{
+		"id",
+		"organisation_id",
+		"attributes.country",
+		"attributes.base_currency",
+		"attributes.name",
+		"attributes.name.1",
+		"attributes.name.2",
+		"attributes.account_classification",
+		"attributes.status",
+	}, fields)
+}
+
+func TestOrganisationAccountValidateErrorMessage(t *testing.T) {
+	t.Parallel()
+
+	account := validAccount()
+	account.Attributes.Country = ""
+	account.Attributes.Status = "closed"
+
+	assert.EqualError(t, account.Validate(), "Error - validation failure list:\n"+
+		"attributes.country in body is required\n"+
+		"attributes.status in body should be one of [pending confirmed failed]")
+}
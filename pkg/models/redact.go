@@ -0,0 +1,90 @@
+package models
+
+import (
+	json2 "encoding/json"
+	"net/url"
+	"strings"
+)
+
+// Redacted replaces the value of a sensitive field
+const Redacted = "[REDACTED]"
+
+// sensitiveAttributes are the OrganisationAccountAttributes, by their json names,
+// that identify the account holder or their account and so shouldn't be logged.
+// Nested objects such as private_identification are redacted as a whole
+var sensitiveAttributes = map[string]bool{
+	"name":                           true,
+	"alternative_names":              true,
+	"bank_account_name":              true,
+	"alternative_bank_account_names": true,
+	"account_number":                 true,
+	"iban":                           true,
+	"customer_id":                    true,
+	"secondary_identification":       true,
+	"user_defined_information":       true,
+	"user_defined_data":              true,
+	"private_identification":         true,
+	"organisation_identification":    true,
+}
+
+// IsSensitiveAttribute is true for the attributes, by their json names,
+// that are redacted by RedactJson and RedactQuery
+func IsSensitiveAttribute(name string) bool {
+	return sensitiveAttributes[name]
+}
+
+// RedactJson replaces the values of sensitive attributes, wherever they are in the
+// json, with Redacted so it's safe to log. The json is compacted, and if it can't
+// be parsed it's replaced entirely as it may still contain sensitive values
+func RedactJson(body []byte) []byte {
+	var value interface{}
+	if err := json2.Unmarshal(body, &value); err != nil {
+		redacted, _ := json2.Marshal(Redacted)
+		return redacted
+	}
+
+	redacted, err := json2.Marshal(redactValue(value))
+	if err != nil {
+		redacted, _ = json2.Marshal(Redacted)
+	}
+	return redacted
+}
+
+func redactValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, field := range value {
+			if sensitiveAttributes[key] {
+				value[key] = Redacted
+			} else {
+				value[key] = redactValue(field)
+			}
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = redactValue(item)
+		}
+	}
+	return value
+}
+
+// RedactQuery replaces the values of filters on sensitive attributes,
+// e.g. filter[iban], in a url's query string with Redacted
+func RedactQuery(fullUrl *url.URL) *url.URL {
+	query := fullUrl.Query()
+	changed := false
+	for key := range query {
+		name := strings.TrimSuffix(strings.TrimPrefix(key, "filter["), "]")
+		if sensitiveAttributes[name] {
+			query[key] = []string{Redacted}
+			changed = true
+		}
+	}
+	if !changed {
+		return fullUrl
+	}
+
+	redacted := *fullUrl
+	redacted.RawQuery = query.Encode()
+	return &redacted
+}
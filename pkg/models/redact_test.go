@@ -0,0 +1,57 @@
+// +build unit
+
+package models
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactJson(t *testing.T) {
+	payload := `{
+  "data": {
+    "id": "48e51a61-29e2-44e6-a97d-4bcf3bda92fc",
+    "attributes": {
+      "country": "GB",
+      "bank_id": "400300",
+      "account_number": "41426819",
+      "iban": "GB11NWBK40030041426819",
+      "name": ["Samantha Holder"],
+      "private_identification": {"first_name": "Samantha", "birth_date": "2017-07-23"},
+      "user_defined_data": [{"key": "Some account related key", "value": "Some account related value"}]
+    }
+  }
+}`
+
+	assert.JSONEq(t, `{
+  "data": {
+    "id": "48e51a61-29e2-44e6-a97d-4bcf3bda92fc",
+    "attributes": {
+      "country": "GB",
+      "bank_id": "400300",
+      "account_number": "[REDACTED]",
+      "iban": "[REDACTED]",
+      "name": "[REDACTED]",
+      "private_identification": "[REDACTED]",
+      "user_defined_data": "[REDACTED]"
+    }
+  }
+}`, string(RedactJson([]byte(payload))))
+}
+
+func TestRedactJsonThatCantBeParsed(t *testing.T) {
+	assert.Equal(t, `"[REDACTED]"`, string(RedactJson([]byte(`{"iban": "GB11NWBK40030041426819"`))))
+}
+
+func TestRedactQuery(t *testing.T) {
+	fullUrl, err := url.Parse("http://localhost:8080/v1/organisation/accounts?filter%5Baccount_number%5D=41426819&filter%5Bcountry%5D=GB")
+	assert.NoError(t, err)
+
+	redacted := RedactQuery(fullUrl)
+	assert.Equal(t, "http://localhost:8080/v1/organisation/accounts?filter%5Baccount_number%5D=%5BREDACTED%5D&filter%5Bcountry%5D=GB", redacted.String())
+	assert.Contains(t, fullUrl.String(), "41426819")
+	assert.True(t, IsSensitiveAttribute("iban"))
+	assert.False(t, IsSensitiveAttribute("country"))
+}